@@ -0,0 +1,236 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"math"
+	"strings"
+	"unicode"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+type AnimationEffect uint8
+
+const (
+	AnimationNone AnimationEffect = iota
+	AnimationHueShift
+	AnimationPulse
+	AnimationTypewriter
+)
+
+var animationEffects = map[string]AnimationEffect{
+	"none":       AnimationNone,
+	"hue":        AnimationHueShift,
+	"pulse":      AnimationPulse,
+	"typewriter": AnimationTypewriter,
+}
+
+var animationEffectNames = []string{"none", "hue", "pulse", "typewriter"}
+
+const defaultAnimationEffect = "none"
+
+// Animations are rendered and dithered frame by frame, so the frame count and
+// size are capped to keep a request within a couple of seconds.
+const (
+	maxAnimationFrames = 24
+	maxAnimationSize   = 256
+	pulseFrames        = 16
+)
+
+// Frame delays are in hundredths of a second. The last typewriter frame
+// stays up longer so the full text can be read before the loop restarts.
+const (
+	animationFrameDelay = 8
+	typewriterHoldDelay = 200
+)
+
+// animationGifPaletteSize leaves one slot of the 256 GIF colors for
+// transparency.
+const animationGifPaletteSize = 255
+
+// maxPaletteSamples caps the pixels the shared palette is picked from, they
+// are spread evenly over all frames.
+const maxPaletteSamples = 1 << 16
+
+// animationFrame is what changes between the frames of an animation. The
+// zero value renders a still image. HueShift turns the gradient colors by
+// that many degrees, RingPulse widens the output ring by that fraction and a
+// positive Reveal draws only that many characters of the text.
+type animationFrame struct {
+	HueShift  float64
+	RingPulse float64
+	Reveal    int
+}
+
+// animationFrames returns the frames of effect for text, at most
+// maxAnimationFrames of them.
+func animationFrames(effect AnimationEffect, text string) []animationFrame {
+	var frames []animationFrame
+
+	switch effect {
+	case AnimationHueShift:
+		for i := 0; i < maxAnimationFrames; i++ {
+			frames = append(frames, animationFrame{HueShift: 360 * float64(i) / maxAnimationFrames})
+		}
+
+	case AnimationPulse:
+		for i := 0; i < pulseFrames; i++ {
+			frames = append(frames, animationFrame{RingPulse: 0.5 * math.Sin(2*math.Pi*float64(i)/pulseFrames)})
+		}
+
+	case AnimationTypewriter:
+		// Long text is typed several characters per frame.
+		units := len(typedUnits(text))
+		count := min(units, maxAnimationFrames)
+		for i := 1; i <= count; i++ {
+			frames = append(frames, animationFrame{Reveal: (units*i + count - 1) / count})
+		}
+	}
+
+	if len(frames) == 0 {
+		frames = append(frames, animationFrame{})
+	}
+	return frames
+}
+
+// typedUnits splits text into the pieces that appear together while it is
+// typed: emoji clusters, and characters with the marks and joiners after
+// them.
+func typedUnits(text string) []string {
+	var units []string
+	for _, run := range splitEmoji(text) {
+		if run.Emoji {
+			units = append(units, run.Text)
+			continue
+		}
+
+		for _, r := range run.Text {
+			joins := unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc, unicode.Variation_Selector) || r == '\u200c' || r == '\u200d'
+			if joins && len(units) > 0 {
+				units[len(units)-1] += string(r)
+				continue
+			}
+			units = append(units, string(r))
+		}
+	}
+	return units
+}
+
+// typedPrefix returns the first count typed units of text and how many of
+// count are left for the following lines.
+func typedPrefix(text string, count int) (string, int) {
+	units := typedUnits(text)
+	if count >= len(units) {
+		return text, count - len(units)
+	}
+	return strings.Join(units[:count], ""), 0
+}
+
+// RenderAnimation renders the template once per frame of effect onto a copy
+// of avatar scaled down to maxAnimationSize. All frames share one palette
+// picked from their colors and are dithered against it, so the colors don't
+// flicker between frames. The returned layout is the one of the last frame.
+func RenderAnimation(avatar *image.NRGBA, tmpl Template, text string, settings UserSettings, effect AnimationEffect) (*gif.GIF, TextLayout, error) {
+	base := fitAnimationSize(avatar)
+	frames := animationFrames(effect, text)
+
+	faces, err := templateFaces(base, tmpl)
+	if err != nil {
+		return nil, TextLayout{}, err
+	}
+
+	images := make([]*image.NRGBA, len(frames))
+	var layout TextLayout
+	for i, frame := range frames {
+		img := image.NewNRGBA(base.Bounds())
+		copy(img.Pix, base.Pix)
+
+		layout, err = renderTemplateFrame(img, tmpl, text, settings, faces, frame)
+		if err != nil {
+			return nil, TextLayout{}, err
+		}
+
+		flattenAlpha(img)
+		images[i] = img
+	}
+
+	palette := animationPalette(images)
+	animation := &gif.GIF{}
+	for _, img := range images {
+		paletted := image.NewPaletted(img.Bounds(), palette)
+		draw.FloydSteinberg.Draw(paletted, img.Bounds(), img, img.Bounds().Min)
+
+		animation.Image = append(animation.Image, paletted)
+		animation.Delay = append(animation.Delay, animationFrameDelay)
+		animation.Disposal = append(animation.Disposal, gif.DisposalBackground)
+	}
+	if effect == AnimationTypewriter {
+		animation.Delay[len(animation.Delay)-1] = typewriterHoldDelay
+	}
+
+	return animation, layout, nil
+}
+
+func fitAnimationSize(img *image.NRGBA) *image.NRGBA {
+	bounds := img.Bounds()
+	if bounds.Dx() <= maxAnimationSize && bounds.Dy() <= maxAnimationSize {
+		return img
+	}
+
+	scale := float64(maxAnimationSize) / float64(max(bounds.Dx(), bounds.Dy()))
+	scaled := image.NewNRGBA(image.Rect(0, 0, max(1, int(float64(bounds.Dx())*scale)), max(1, int(float64(bounds.Dy())*scale))))
+	xdraw.BiLinear.Scale(scaled, scaled.Bounds(), img, bounds, draw.Src, nil)
+	return scaled
+}
+
+// flattenAlpha makes every pixel either opaque or fully transparent, since
+// GIF has no partial transparency.
+func flattenAlpha(img *image.NRGBA) {
+	for i := 3; i < len(img.Pix); i += 4 {
+		if img.Pix[i] < 128 {
+			copy(img.Pix[i-3:i+1], []uint8{0, 0, 0, 0})
+		} else {
+			img.Pix[i] = 255
+		}
+	}
+}
+
+// animationPalette picks the shared palette of the frames with medianCut,
+// adding a transparent color when any frame has transparent pixels.
+func animationPalette(images []*image.NRGBA) color.Palette {
+	var samples []color.NRGBA
+	transparent := false
+
+	pixels := 0
+	for _, img := range images {
+		pixels += len(img.Pix) / 4
+	}
+	step := max(1, pixels/maxPaletteSamples)
+
+	for _, img := range images {
+		for i := 0; i < len(img.Pix); i += 4 {
+			if img.Pix[i+3] == 0 {
+				transparent = true
+				continue
+			}
+			if i/4%step == 0 {
+				samples = append(samples, color.NRGBA{R: img.Pix[i], G: img.Pix[i+1], B: img.Pix[i+2], A: 255})
+			}
+		}
+	}
+
+	var palette color.Palette
+	if transparent {
+		palette = append(palette, color.NRGBA{})
+	}
+	for _, c := range medianCut(samples, animationGifPaletteSize) {
+		palette = append(palette, c.Color)
+	}
+	if len(palette) == 0 {
+		palette = append(palette, color.NRGBA{})
+	}
+	return palette
+}
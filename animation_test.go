@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTypedUnits(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected []string
+	}{
+		{name: "Plain text", text: "Hi!", expected: []string{"H", "i", "!"}},
+		{name: "Combining mark", text: "Café", expected: []string{"C", "a", "f", "é"}},
+		{name: "Emoji cluster", text: "Go 👩‍💻", expected: []string{"G", "o", " ", "👩‍💻"}},
+		{name: "Empty", text: "", expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := typedUnits(tt.text); !slices.Equal(actual, tt.expected) {
+				t.Errorf("Expected %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestAnimationFrames(t *testing.T) {
+	tests := []struct {
+		name        string
+		effect      AnimationEffect
+		text        string
+		count       int
+		firstReveal int
+		lastReveal  int
+	}{
+		{name: "Still", effect: AnimationNone, text: "Hi", count: 1},
+		{name: "Hue shift", effect: AnimationHueShift, text: "Hi", count: maxAnimationFrames},
+		{name: "Pulse", effect: AnimationPulse, text: "Hi", count: pulseFrames},
+		{name: "Short typewriter", effect: AnimationTypewriter, text: "Day off", count: 7, firstReveal: 1, lastReveal: 7},
+		{name: "Long typewriter is capped", effect: AnimationTypewriter, text: "Out of office until the end of next week", count: maxAnimationFrames, firstReveal: 2, lastReveal: 40},
+		{name: "Typewriter without text", effect: AnimationTypewriter, text: "", count: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frames := animationFrames(tt.effect, tt.text)
+			if len(frames) != tt.count {
+				t.Fatalf("Expected %d frames, got %d", tt.count, len(frames))
+			}
+			if frames[0].Reveal != tt.firstReveal || frames[len(frames)-1].Reveal != tt.lastReveal {
+				t.Errorf("Expected reveal from %d to %d, got %d to %d", tt.firstReveal, tt.lastReveal, frames[0].Reveal, frames[len(frames)-1].Reveal)
+			}
+		})
+	}
+}
+
+func TestRenderAnimation(t *testing.T) {
+	if err := LoadTemplates(); err != nil {
+		t.Fatalf("LoadTemplates failed: %v", err)
+	}
+
+	settings := defaultUserSettings()
+	settings.OutputShape = outputShapes["circle"]
+
+	for _, name := range []string{"hue", "pulse", "typewriter"} {
+		t.Run(name, func(t *testing.T) {
+			avatar := solidImage(640, color.NRGBA{R: 120, G: 90, B: 60, A: 255})
+
+			started := time.Now()
+			animation, _, err := RenderAnimation(avatar, renderTemplates[defaultTemplate], "Day off!", settings, animationEffects[name])
+			if err != nil {
+				t.Fatalf("RenderAnimation failed: %v", err)
+			}
+			t.Logf("Rendered %d frames in %v", len(animation.Image), time.Since(started))
+
+			if len(animation.Image) > maxAnimationFrames {
+				t.Errorf("Expected at most %d frames, got %d", maxAnimationFrames, len(animation.Image))
+			}
+			for _, frame := range animation.Image {
+				if frame.Bounds() != image.Rect(0, 0, maxAnimationSize, maxAnimationSize) {
+					t.Fatalf("Expected frames scaled to %d, got %v", maxAnimationSize, frame.Bounds())
+				}
+				if !slices.Equal(frame.Palette, animation.Image[0].Palette) {
+					t.Fatal("Expected every frame to share the palette")
+				}
+			}
+			if corner := animation.Image[0].At(0, 0); corner != (color.NRGBA{}) {
+				t.Errorf("Expected transparent corners, got %v", corner)
+			}
+
+			var buf bytes.Buffer
+			if err := gif.EncodeAll(&buf, animation); err != nil {
+				t.Fatalf("Encoding failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestRenderAnimationTypesText(t *testing.T) {
+	if err := LoadTemplates(); err != nil {
+		t.Fatalf("LoadTemplates failed: %v", err)
+	}
+
+	avatar := solidImage(200, color.NRGBA{R: 0, G: 0, B: 0, A: 255})
+	animation, _, err := RenderAnimation(avatar, renderTemplates[defaultTemplate], "Hello", defaultUserSettings(), AnimationTypewriter)
+	if err != nil {
+		t.Fatalf("RenderAnimation failed: %v", err)
+	}
+
+	brightPixels := func(frame *image.Paletted) int {
+		count := 0
+		for i := range frame.Pix {
+			r, g, b, _ := frame.Palette[frame.Pix[i]].RGBA()
+			if r > 0xe000 && g > 0xe000 && b > 0xe000 {
+				count++
+			}
+		}
+		return count
+	}
+
+	previous := 0
+	for i, frame := range animation.Image {
+		count := brightPixels(frame)
+		if count <= previous {
+			t.Errorf("Expected frame %d to show more text than the one before, got %d pixels after %d", i, count, previous)
+		}
+		previous = count
+	}
+	if last := animation.Delay[len(animation.Delay)-1]; last != typewriterHoldDelay {
+		t.Errorf("Expected the last frame to stay for %d, got %d", typewriterHoldDelay, last)
+	}
+}
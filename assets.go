@@ -0,0 +1,128 @@
+package main
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+//go:embed assets
+var embeddedAssets embed.FS
+
+// assets resolves every asset path the bot uses, such as "Roboto-Regular.ttf"
+// or "emoji/1f3d6.png". It starts with the files embedded in the binary, and
+// UseAssetOverrideDir puts an operator's directory in front of them.
+var assets fs.FS = bundledAssets()
+
+func bundledAssets() fs.FS {
+	bundled, err := fs.Sub(embeddedAssets, "assets")
+	if err != nil {
+		panic(err)
+	}
+	return bundled
+}
+
+// overlayFS looks files up in each layer in turn. Directory listings are
+// merged, with entries of earlier layers hiding the ones of later layers.
+type overlayFS []fs.FS
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	for _, layer := range o {
+		file, err := layer.Open(name)
+		if err == nil || !errors.Is(err, fs.ErrNotExist) {
+			return file, err
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+func (o overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	var merged []fs.DirEntry
+	seen := map[string]bool{}
+	found := false
+
+	for _, layer := range o {
+		entries, err := fs.ReadDir(layer, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		found = true
+		for _, entry := range entries {
+			if !seen[entry.Name()] {
+				seen[entry.Name()] = true
+				merged = append(merged, entry)
+			}
+		}
+	}
+
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Name() < merged[j].Name() })
+	return merged, nil
+}
+
+// UseAssetOverrideDir makes files in dir take precedence over the embedded
+// assets and registers the font families found in its fonts directory. It
+// has to be called at startup, before anything is loaded and cached.
+func UseAssetOverrideDir(dir string) ([]string, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error opening assets directory: %v", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("assets path %s is not a directory", dir)
+	}
+
+	assets = overlayFS{os.DirFS(dir), bundledAssets()}
+
+	return discoverFontFamilies()
+}
+
+// assetFiles lists files in dir matching any of the patterns, sorted by name.
+func assetFiles(dir string, patterns ...string) []string {
+	var files []string
+	for _, pattern := range patterns {
+		matches, _ := fs.Glob(assets, path.Join(dir, pattern))
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+	return files
+}
+
+var fontFilePatterns = []string{"*.ttf", "*.otf"}
+
+// discoverFontFamilies registers every font in fontFamilyDir that no family
+// uses yet, named after its file, e.g. fonts/Pacifico.ttf becomes "pacifico".
+func discoverFontFamilies() ([]string, error) {
+	known := map[string]bool{}
+	for _, fontPath := range fontFamilies {
+		known[fontPath] = true
+	}
+
+	var discovered []string
+	for _, fontPath := range assetFiles(fontFamilyDir, fontFilePatterns...) {
+		if known[fontPath] {
+			continue
+		}
+
+		name := strings.ToLower(strings.TrimSuffix(path.Base(fontPath), path.Ext(fontPath)))
+		if _, exists := fontFamilies[name]; exists {
+			return nil, fmt.Errorf("font %s clashes with the %s font family", fontPath, name)
+		}
+
+		fontFamilies[name] = fontPath
+		fontFamilyNames = append(fontFamilyNames, name)
+		discovered = append(discovered, name)
+	}
+	return discovered, nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestOverlayFS(t *testing.T) {
+	override := fstest.MapFS{
+		"fonts/custom.ttf": {Data: []byte("override")},
+		"shared.txt":       {Data: []byte("override")},
+	}
+	base := fstest.MapFS{
+		"fonts/base.ttf": {Data: []byte("base")},
+		"shared.txt":     {Data: []byte("base")},
+	}
+	overlay := overlayFS{override, base}
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{name: "Override wins", path: "shared.txt", expected: "override"},
+		{name: "Override only", path: "fonts/custom.ttf", expected: "override"},
+		{name: "Falls through to base", path: "fonts/base.ttf", expected: "base"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := fs.ReadFile(overlay, tt.path)
+			if err != nil {
+				t.Fatalf("ReadFile failed: %v", err)
+			}
+			if string(data) != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, data)
+			}
+		})
+	}
+
+	if _, err := overlay.Open("missing.txt"); !os.IsNotExist(err) {
+		t.Errorf("Expected a not exist error, got %v", err)
+	}
+
+	matches, err := fs.Glob(overlay, "fonts/*.ttf")
+	if err != nil {
+		t.Fatalf("Glob failed: %v", err)
+	}
+	if expected := []string{"fonts/base.ttf", "fonts/custom.ttf"}; !slices.Equal(matches, expected) {
+		t.Errorf("Expected %v, got %v", expected, matches)
+	}
+}
+
+func TestLoadFontOutsideWorkingDirectory(t *testing.T) {
+	workingDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd failed: %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("Chdir failed: %v", err)
+	}
+	defer os.Chdir(workingDir)
+
+	for _, name := range fontFamilyNames {
+		if _, _, err := LoadFont(fontFamilies[name]); err != nil {
+			t.Errorf("Failed to load %s from the embedded assets: %v", name, err)
+		}
+	}
+}
+
+func TestUseAssetOverrideDir(t *testing.T) {
+	originalAssets := assets
+	originalFamilies := make(map[string]string, len(fontFamilies))
+	for name, fontPath := range fontFamilies {
+		originalFamilies[name] = fontPath
+	}
+	originalNames := fontFamilyNames
+	defer func() {
+		assets = originalAssets
+		fontFamilies = originalFamilies
+		fontFamilyNames = originalNames
+	}()
+
+	fontBytes, err := fs.ReadFile(assets, fontFamilies["italic"])
+	if err != nil {
+		t.Fatalf("Failed to read font: %v", err)
+	}
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "fonts"), 0o755); err != nil {
+		t.Fatalf("MkdirAll failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "fonts", "Handwritten.ttf"), fontBytes, 0o644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	discovered, err := UseAssetOverrideDir(dir)
+	if err != nil {
+		t.Fatalf("UseAssetOverrideDir failed: %v", err)
+	}
+	if !slices.Equal(discovered, []string{"handwritten"}) {
+		t.Fatalf("Expected the handwritten family to be discovered, got %v", discovered)
+	}
+	if !slices.Contains(fontFamilyNames, "handwritten") {
+		t.Error("Expected the handwritten family to be offered in the menu")
+	}
+	if _, err := CachedLoadFontFamily("handwritten"); err != nil {
+		t.Errorf("Failed to load the discovered family: %v", err)
+	}
+	if _, err := CachedLoadFontFamily(defaultFontFamily); err != nil {
+		t.Errorf("Failed to load the embedded family: %v", err)
+	}
+}
+
+func TestUseAssetOverrideDirMissing(t *testing.T) {
+	originalAssets := assets
+	defer func() { assets = originalAssets }()
+
+	if _, err := UseAssetOverrideDir(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Expected an error for a missing directory")
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// autoGradientPalette picks the gradient colors from the avatar itself
+// instead of a fixed palette.
+const autoGradientPalette = "auto"
+
+// Colors are picked from a copy of the avatar at most paletteSampleSize wide,
+// reduced to paletteCandidates colors.
+const (
+	paletteSampleSize = 64
+	paletteCandidates = 8
+)
+
+// Two picked colors closer than minPaletteDistance in OKLab look like one,
+// the second color is then made up from the first.
+const minPaletteDistance = 0.12
+
+// harmonyHueShift is how far the made up second color is turned from the
+// first, close enough to stay in the same family of colors.
+const harmonyHueShift = 40
+
+// AvatarPalette builds a two color gradient from the dominant colors of img.
+// Colorful colors are preferred over greys of the same share, so a small
+// bright shirt wins over a large grey wall. The darker color comes first.
+func AvatarPalette(img *image.NRGBA) []GradientStop {
+	candidates := medianCut(paletteSamples(img), paletteCandidates)
+	if len(candidates) == 0 {
+		return gradientPalettes[defaultGradientPalette]
+	}
+
+	score := func(c paletteColor) float64 {
+		lab := ColorToOKLab(c.Color)
+		return float64(c.Weight) * (math.Hypot(lab.A, lab.B) + 0.05)
+	}
+
+	primary := candidates[0]
+	for _, candidate := range candidates[1:] {
+		if score(candidate) > score(primary) {
+			primary = candidate
+		}
+	}
+	primaryLab := ColorToOKLab(primary.Color)
+
+	var secondary *paletteColor
+	for i, candidate := range candidates {
+		lab := ColorToOKLab(candidate.Color)
+		distance := math.Sqrt(sqr(lab.L-primaryLab.L) + sqr(lab.A-primaryLab.A) + sqr(lab.B-primaryLab.B))
+		if distance < minPaletteDistance {
+			continue
+		}
+		if secondary == nil || score(candidate) > score(*secondary) {
+			secondary = &candidates[i]
+		}
+	}
+
+	start, end := primary.Color, harmonyColor(primary.Color)
+	if secondary != nil {
+		end = secondary.Color
+	}
+	if ColorToOKLab(start).L > ColorToOKLab(end).L {
+		start, end = end, start
+	}
+
+	return []GradientStop{
+		{Position: 0, Color: start},
+		{Position: 1, Color: end},
+	}
+}
+
+// harmonyColor is an analogous color to c, turned along the hue circle and
+// shifted in lightness towards the middle so the gradient stays visible on
+// very dark and very light photos alike.
+func harmonyColor(c color.NRGBA) color.NRGBA {
+	lab := ColorToOKLab(RotateHue(c, harmonyHueShift))
+	if lab.L > 0.5 {
+		lab.L -= 0.25
+	} else {
+		lab.L += 0.25
+	}
+	return OKLabToColor(lab, 255)
+}
+
+// paletteSamples returns the opaque pixels of a downscaled copy of img.
+func paletteSamples(img *image.NRGBA) []color.NRGBA {
+	bounds := img.Bounds()
+	if bounds.Empty() {
+		return nil
+	}
+
+	scale := min(1, float64(paletteSampleSize)/float64(max(bounds.Dx(), bounds.Dy())))
+	small := image.NewNRGBA(image.Rect(0, 0, max(1, int(float64(bounds.Dx())*scale)), max(1, int(float64(bounds.Dy())*scale))))
+	xdraw.ApproxBiLinear.Scale(small, small.Bounds(), img, bounds, draw.Src, nil)
+
+	samples := make([]color.NRGBA, 0, len(small.Pix)/4)
+	for i := 0; i < len(small.Pix); i += 4 {
+		if small.Pix[i+3] < 128 {
+			continue
+		}
+		samples = append(samples, color.NRGBA{R: small.Pix[i], G: small.Pix[i+1], B: small.Pix[i+2], A: 255})
+	}
+	return samples
+}
+
+func sqr(value float64) float64 {
+	return value * value
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestAvatarPalette(t *testing.T) {
+	red := color.NRGBA{R: 220, G: 30, B: 30, A: 255}
+	blue := color.NRGBA{R: 20, G: 40, B: 200, A: 255}
+	grey := color.NRGBA{R: 120, G: 120, B: 120, A: 255}
+	white := color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+
+	halves := func(top, bottom color.NRGBA, split int) *image.NRGBA {
+		img := solidImage(100, bottom)
+		draw.Draw(img, image.Rect(0, 0, 100, split), image.NewUniform(top), image.Point{}, draw.Src)
+		return img
+	}
+
+	stripes := func(small, medium, large color.NRGBA) *image.NRGBA {
+		img := halves(medium, large, 50)
+		draw.Draw(img, image.Rect(0, 0, 100, 15), image.NewUniform(small), image.Point{}, draw.Src)
+		return img
+	}
+
+	tests := []struct {
+		name     string
+		img      *image.NRGBA
+		expected []color.NRGBA
+	}{
+		{
+			name:     "Two colors, darker first",
+			img:      halves(red, blue, 50),
+			expected: []color.NRGBA{blue, red},
+		},
+		{
+			name:     "Colorful patch beats a larger grey area",
+			img:      stripes(red, white, grey),
+			expected: []color.NRGBA{red, grey},
+		},
+		{
+			name:     "Single color gets a lighter neighbour",
+			img:      solidImage(100, blue),
+			expected: []color.NRGBA{blue, harmonyColor(blue)},
+		},
+		{
+			name:     "Transparent avatar keeps the default palette",
+			img:      image.NewNRGBA(image.Rect(0, 0, 100, 100)),
+			expected: []color.NRGBA{gradientPalettes[defaultGradientPalette][0].Color, gradientPalettes[defaultGradientPalette][1].Color},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stops := AvatarPalette(tt.img)
+			if len(stops) != len(tt.expected) {
+				t.Fatalf("Expected %d stops, got %v", len(tt.expected), stops)
+			}
+			for i, stop := range stops {
+				if stop.Color != tt.expected[i] {
+					t.Errorf("Expected stop %d to be %v, got %v", i, tt.expected[i], stop.Color)
+				}
+			}
+		})
+	}
+}
+
+func TestHarmonyColor(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   color.NRGBA
+		lighter bool
+	}{
+		{name: "Dark colors get lighter", input: color.NRGBA{R: 20, G: 40, B: 120, A: 255}, lighter: true},
+		{name: "Light colors get darker", input: color.NRGBA{R: 250, G: 220, B: 160, A: 255}, lighter: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, actual := ColorToOKLab(tt.input), ColorToOKLab(harmonyColor(tt.input))
+			if (actual.L > input.L) != tt.lighter {
+				t.Errorf("Expected lighter to be %v, got lightness %.2f from %.2f", tt.lighter, actual.L, input.L)
+			}
+		})
+	}
+}
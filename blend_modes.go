@@ -0,0 +1,140 @@
+package main
+
+import (
+	"image/color"
+	"math"
+)
+
+// BlendMode combines a base pixel with an overlay pixel before the result is
+// mixed back into the base with the overlay opacity. The formulas follow the
+// W3C Compositing and Blending spec, which matches Photoshop.
+type BlendMode uint8
+
+const (
+	BlendNormal BlendMode = iota
+	BlendMultiply
+	BlendScreen
+	BlendOverlay
+	BlendSoftLight
+	BlendColor
+	BlendLuminosity
+)
+
+var blendModes = map[string]BlendMode{
+	"normal":     BlendNormal,
+	"multiply":   BlendMultiply,
+	"screen":     BlendScreen,
+	"overlay":    BlendOverlay,
+	"soft-light": BlendSoftLight,
+	"color":      BlendColor,
+	"luminosity": BlendLuminosity,
+}
+
+var blendModeNames = []string{"normal", "multiply", "screen", "overlay", "soft-light", "color", "luminosity"}
+
+type rgb struct {
+	R, G, B float64
+}
+
+func toRGB(c color.NRGBA) rgb {
+	return rgb{R: float64(c.R) / 255, G: float64(c.G) / 255, B: float64(c.B) / 255}
+}
+
+func (c rgb) toNRGBA(alpha uint8) color.NRGBA {
+	return color.NRGBA{
+		R: uint8(math.Round(clampUnit(c.R) * 255)),
+		G: uint8(math.Round(clampUnit(c.G) * 255)),
+		B: uint8(math.Round(clampUnit(c.B) * 255)),
+		A: alpha,
+	}
+}
+
+// BlendPixels applies mode to base and overlay. The result keeps the alpha of
+// the overlay so it can be mixed with the base like a normal overlay pixel.
+func BlendPixels(base, overlay color.NRGBA, mode BlendMode) color.NRGBA {
+	if mode == BlendNormal {
+		return overlay
+	}
+
+	b, s := toRGB(base), toRGB(overlay)
+
+	var result rgb
+	switch mode {
+	case BlendMultiply:
+		result = separableBlend(b, s, func(b, s float64) float64 { return b * s })
+	case BlendScreen:
+		result = separableBlend(b, s, screenChannel)
+	case BlendOverlay:
+		result = separableBlend(b, s, func(b, s float64) float64 { return hardLightChannel(s, b) })
+	case BlendSoftLight:
+		result = separableBlend(b, s, softLightChannel)
+	case BlendColor:
+		result = setLuminosity(s, luminosity(b))
+	case BlendLuminosity:
+		result = setLuminosity(b, luminosity(s))
+	default:
+		result = s
+	}
+
+	return result.toNRGBA(overlay.A)
+}
+
+func separableBlend(b, s rgb, blend func(b, s float64) float64) rgb {
+	return rgb{R: blend(b.R, s.R), G: blend(b.G, s.G), B: blend(b.B, s.B)}
+}
+
+func screenChannel(b, s float64) float64 {
+	return b + s - b*s
+}
+
+func hardLightChannel(b, s float64) float64 {
+	if s <= 0.5 {
+		return b * 2 * s
+	}
+	return screenChannel(b, 2*s-1)
+}
+
+func softLightChannel(b, s float64) float64 {
+	if s <= 0.5 {
+		return b - (1-2*s)*b*(1-b)
+	}
+
+	var d float64
+	if b <= 0.25 {
+		d = ((16*b-12)*b + 4) * b
+	} else {
+		d = math.Sqrt(b)
+	}
+	return b + (2*s-1)*(d-b)
+}
+
+func luminosity(c rgb) float64 {
+	return 0.3*c.R + 0.59*c.G + 0.11*c.B
+}
+
+func setLuminosity(c rgb, lum float64) rgb {
+	d := lum - luminosity(c)
+	return clipColor(rgb{R: c.R + d, G: c.G + d, B: c.B + d})
+}
+
+func clipColor(c rgb) rgb {
+	l := luminosity(c)
+	n := math.Min(c.R, math.Min(c.G, c.B))
+	x := math.Max(c.R, math.Max(c.G, c.B))
+
+	if n < 0 {
+		c = rgb{
+			R: l + (c.R-l)*l/(l-n),
+			G: l + (c.G-l)*l/(l-n),
+			B: l + (c.B-l)*l/(l-n),
+		}
+	}
+	if x > 1 {
+		c = rgb{
+			R: l + (c.R-l)*(1-l)/(x-l),
+			G: l + (c.G-l)*(1-l)/(x-l),
+			B: l + (c.B-l)*(1-l)/(x-l),
+		}
+	}
+	return c
+}
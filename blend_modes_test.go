@@ -0,0 +1,74 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func blendTestImages() (*image.NRGBA, *image.NRGBA) {
+	base := CreateGradient(64, 64, GradientSpec{
+		Shape: LinearGradient(90),
+		Stops: []GradientStop{
+			{Position: 0, Color: color.NRGBA{R: 20, G: 20, B: 20, A: 255}},
+			{Position: 0.33, Color: color.NRGBA{R: 230, G: 180, B: 40, A: 255}},
+			{Position: 0.66, Color: color.NRGBA{R: 60, G: 170, B: 90, A: 255}},
+			{Position: 1, Color: color.NRGBA{R: 240, G: 240, B: 240, A: 255}},
+		},
+	})
+	overlay := CreateGradient(64, 64, TwoColorGradient(LinearGradient(0),
+		color.NRGBA{R: 0, G: 0, B: 255, A: 255}, color.NRGBA{R: 255, G: 0, B: 255, A: 255}))
+
+	return base, overlay
+}
+
+func TestOverlayImageBlendModesWithReference(t *testing.T) {
+	for _, name := range blendModeNames {
+		t.Run(name, func(t *testing.T) {
+			base, overlay := blendTestImages()
+			OverlayImage(base, overlay, 0.5, blendModes[name], ColorSpaceSRGB)
+
+			referenceImg, err := LoadImage("./test_assets/blend_" + name + ".png")
+			if err != nil {
+				t.Fatalf("Failed to load reference image: %v", err)
+			}
+
+			if !CompareImages(base, referenceImg) {
+				t.Error("Generated image does not match the reference image")
+			}
+		})
+	}
+}
+
+func TestBlendPixels(t *testing.T) {
+	grey := color.NRGBA{128, 128, 128, 255}
+	white := color.NRGBA{255, 255, 255, 255}
+	black := color.NRGBA{0, 0, 0, 255}
+	red := color.NRGBA{255, 0, 0, 200}
+
+	tests := []struct {
+		name     string
+		base     color.NRGBA
+		overlay  color.NRGBA
+		mode     BlendMode
+		expected color.NRGBA
+	}{
+		{name: "Normal returns the overlay", base: grey, overlay: red, mode: BlendNormal, expected: red},
+		{name: "Multiply by white keeps the base", base: grey, overlay: white, mode: BlendMultiply, expected: grey},
+		{name: "Multiply by black is black", base: grey, overlay: black, mode: BlendMultiply, expected: black},
+		{name: "Screen with white is white", base: grey, overlay: white, mode: BlendScreen, expected: white},
+		{name: "Screen with black keeps the base", base: grey, overlay: black, mode: BlendScreen, expected: grey},
+		{name: "Overlay keeps black base", base: black, overlay: red, mode: BlendOverlay, expected: color.NRGBA{0, 0, 0, 200}},
+		{name: "Luminosity of a black overlay is black", base: red, overlay: black, mode: BlendLuminosity, expected: color.NRGBA{0, 0, 0, 255}},
+		{name: "Color of grey keeps base luminosity", base: grey, overlay: white, mode: BlendColor, expected: grey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := BlendPixels(tt.base, tt.overlay, tt.mode)
+			if actual != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
@@ -3,18 +3,22 @@ package main
 import (
 	"bytes"
 	"errors"
-	"image/color"
+	"image"
+	"image/gif"
 	"image/png"
 	"os"
+	"strings"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/sirupsen/logrus"
 )
 
 type VacatoBot struct {
-	bot     *tgbotapi.BotAPI
-	logger  *logrus.Logger
-	encoder png.Encoder
+	bot      *tgbotapi.BotAPI
+	logger   *logrus.Logger
+	encoder  png.Encoder
+	settings *SettingsStore
 }
 
 func getUpdateChatId(update tgbotapi.Update) int64 {
@@ -63,43 +67,131 @@ func (vb *VacatoBot) sendMessage(update tgbotapi.Update, text string) {
 }
 
 func (vb *VacatoBot) handleGradient(update tgbotapi.Update) error {
-	logger := vb.getUpdateLogger(update)
 	text := update.Message.Text
+	settings := vb.settings.Get(update.Message.From.ID)
+
+	if period, ok := ParseReturnDate(withoutPlaceholders(text), time.Now().In(settings.Location())); ok {
+		vb.getUpdateLogger(update).WithFields(logrus.Fields{
+			"text": text,
+			"back": period.Back.Format(time.DateOnly),
+		}).Info("Parsed return date")
+
+		vb.settings.Update(update.Message.From.ID, func(settings *UserSettings) {
+			settings.Away = period
+			settings.PendingText = text
+		})
+		vb.sendReturnConfirmation(update, period, settings.Timezone)
+		return nil
+	}
+
+	return vb.renderAvatar(update, text)
+}
+
+// renderAvatar puts text on the user's avatar and sends it back, it serves
+// messages, the buttons of the return date confirmation and /refresh. text is
+// kept as typed, with its placeholders, so it can be rendered again later
+// with fresh values.
+func (vb *VacatoBot) renderAvatar(update tgbotapi.Update, text string) error {
+	logger := vb.getUpdateLogger(update)
+	user := getUpdateUserFrom(update)
+	settings := vb.settings.Get(user.ID)
 
 	logger.WithField("text", text).Info("Handling gradient")
 
-	userAvatar, err := GetUserAvatar(vb.bot, update.Message.From.ID)
+	expanded, err := ExpandPlaceholders(text, placeholderValues(user.FirstName, user.UserName, settings.Away, time.Now()))
+	if err != nil {
+		logger.WithError(err).WithField("text", text).Info("Failed to expand placeholders")
+		vb.sendMessage(update, "Hmm, "+err.Error()+".\n\n"+placeholderHelp)
+		return nil
+	}
+	vb.settings.Update(user.ID, func(settings *UserSettings) {
+		settings.TextTemplate = text
+	})
+
+	userAvatar, err := GetUserAvatar(vb.bot, user.ID)
 	if err != nil {
 		logger.WithError(err).Error("Failed to get user avatar")
 		return err
 	}
 
-	gradient := CachedCreateGradient(
-		userAvatar.Bounds().Dx(), userAvatar.Bounds().Dy(),
-		color.NRGBA{R: 0, G: 0, B: 255, A: 255},
-		color.NRGBA{R: 255, G: 0, B: 255, A: 255},
-	)
+	tmpl, ok := renderTemplates[settings.Template]
+	if !ok {
+		tmpl = renderTemplates[defaultTemplate]
+	}
 
-	OverlayImage(userAvatar, gradient, 0.5)
-	DrawTextToImage(userAvatar, text)
-	DrawSignature(userAvatar)
+	var layout TextLayout
+	if settings.Animation == AnimationNone {
+		layout, err = vb.sendRenderedPhoto(update, userAvatar, tmpl, expanded, settings)
+	} else {
+		layout, err = vb.sendRenderedAnimation(update, userAvatar, tmpl, expanded, settings)
+	}
+	if err != nil {
+		return err
+	}
+
+	if layout.Truncated {
+		vb.sendMessage(update, "Your text didn't quite fit, so I shortened it to:\n\n"+strings.Join(layout.Lines, "\n"))
+	}
+	return nil
+}
+
+func (vb *VacatoBot) sendRenderedPhoto(update tgbotapi.Update, userAvatar *image.NRGBA, tmpl Template, text string, settings UserSettings) (TextLayout, error) {
+	logger := vb.getUpdateLogger(update)
+
+	layout, err := RenderTemplate(userAvatar, tmpl, text, settings)
+	if err != nil {
+		logger.WithError(err).WithField("template", tmpl.Name).Error("Failed to render template")
+		return TextLayout{}, errors.New("error during drawing")
+	}
 
 	var buf bytes.Buffer
 	err = vb.encoder.Encode(&buf, userAvatar)
 	if err != nil {
 		logger.WithError(err).Error("Failed to encode image")
-		return errors.New("error during overlaying")
+		return TextLayout{}, errors.New("error during overlaying")
 	}
 
-	photo := tgbotapi.NewPhoto(update.Message.Chat.ID, tgbotapi.FileBytes{
+	_, err = vb.bot.Send(NewImageMessage(getUpdateChatId(update), userAvatar, tgbotapi.FileBytes{
 		Name:  "avatar_with_gradient.png",
 		Bytes: buf.Bytes(),
-	})
-	_, err = vb.bot.Send(photo)
+	}, "", nil))
 	if err != nil {
 		logger.WithError(err).Error("Failed to send photo")
+		return TextLayout{}, err
+	}
+	return layout, nil
+}
+
+func (vb *VacatoBot) sendRenderedAnimation(update tgbotapi.Update, userAvatar *image.NRGBA, tmpl Template, text string, settings UserSettings) (TextLayout, error) {
+	logger := vb.getUpdateLogger(update)
+
+	animation, layout, err := RenderAnimation(userAvatar, tmpl, text, settings, settings.Animation)
+	if err != nil {
+		logger.WithError(err).WithField("template", tmpl.Name).Error("Failed to render animation")
+		return TextLayout{}, errors.New("error during drawing")
+	}
+
+	var buf bytes.Buffer
+	err = gif.EncodeAll(&buf, animation)
+	if err != nil {
+		logger.WithError(err).Error("Failed to encode animation")
+		return TextLayout{}, errors.New("error during encoding")
+	}
+
+	_, err = vb.bot.Send(tgbotapi.NewAnimation(getUpdateChatId(update), tgbotapi.FileBytes{
+		Name:  "avatar_with_gradient.gif",
+		Bytes: buf.Bytes(),
+	}))
+	if err != nil {
+		logger.WithError(err).Error("Failed to send animation")
+		return TextLayout{}, err
 	}
-	return err
+
+	logger.WithFields(logrus.Fields{
+		"frames": len(animation.Image),
+		"bytes":  buf.Len(),
+	}).Info("Sent animation")
+	return layout, nil
 }
 
 func (vb *VacatoBot) handleMenu(update tgbotapi.Update) {
@@ -119,6 +211,422 @@ func (vb *VacatoBot) handleMenu(update tgbotapi.Update) {
 	}
 }
 
+func choiceLabel(name string) string {
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func choiceKeyboard(callbackPrefix string, names []string) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, name := range names {
+		button := tgbotapi.NewInlineKeyboardButtonData(choiceLabel(name), callbackPrefix+name)
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(button))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+func (vb *VacatoBot) sendChoiceMenu(update tgbotapi.Update, text, callbackPrefix string, names []string) {
+	logger := vb.getUpdateLogger(update)
+	logger.WithField("menu", callbackPrefix).Info("Displaying choice menu")
+
+	msg := tgbotapi.NewMessage(update.Message.Chat.ID, text)
+	msg.ReplyMarkup = choiceKeyboard(callbackPrefix, names)
+
+	_, err := vb.bot.Send(msg)
+	if err != nil {
+		logger.WithError(err).Error("Failed to send choice menu message")
+	}
+}
+
+func (vb *VacatoBot) handleGradientMenu(update tgbotapi.Update) {
+	vb.sendChoiceMenu(update, "Pick the gradient shape for your next avatar.", gradientCallbackPrefix, gradientShapeNames)
+}
+
+func (vb *VacatoBot) handlePaletteMenu(update tgbotapi.Update) {
+	vb.sendChoiceMenu(update, "Pick the colors for your next avatar. Auto picks them from your photo.", paletteCallbackPrefix, gradientPaletteNames)
+}
+
+func (vb *VacatoBot) handlePaletteChoice(update tgbotapi.Update, name string) {
+	logger := vb.getUpdateLogger(update)
+
+	if _, ok := gradientPalettes[name]; !ok && name != autoGradientPalette {
+		logger.Errorf("Unknown palette %s", name)
+		vb.sendMessage(update, "Hmm, I don't know those colors. Try /palette again!")
+		return
+	}
+
+	vb.settings.Update(update.CallbackQuery.From.ID, func(settings *UserSettings) {
+		settings.Palette = name
+	})
+
+	logger.WithField("palette", name).Info("Palette selected")
+	if name == autoGradientPalette {
+		vb.sendMessage(update, "Got it! Your next avatar will use colors picked from your photo.")
+		return
+	}
+	vb.sendMessage(update, "Got it! Your next avatar will use the "+name+" colors.")
+}
+
+func (vb *VacatoBot) handleGradientChoice(update tgbotapi.Update, name string) {
+	logger := vb.getUpdateLogger(update)
+
+	shape, ok := gradientShapes[name]
+	if !ok {
+		logger.Errorf("Unknown gradient shape %s", name)
+		vb.sendMessage(update, "Hmm, I don't know that gradient. Try /gradient again!")
+		return
+	}
+
+	vb.settings.Update(update.CallbackQuery.From.ID, func(settings *UserSettings) {
+		settings.GradientShape = shape
+	})
+
+	logger.WithField("gradient", name).Info("Gradient shape selected")
+	vb.sendMessage(update, "Got it! Your next avatar will use the "+name+" gradient.")
+}
+
+func (vb *VacatoBot) handleColorSpaceMenu(update tgbotapi.Update) {
+	vb.sendChoiceMenu(update, "Pick how the gradient colors blend into each other. OKLab and OKLCH keep the middle bright, sRGB is the classic look.", colorSpaceCallbackPrefix, colorSpaceNames)
+}
+
+func (vb *VacatoBot) handleColorSpaceChoice(update tgbotapi.Update, name string) {
+	logger := vb.getUpdateLogger(update)
+
+	space, ok := colorSpaces[name]
+	if !ok {
+		logger.Errorf("Unknown color space %s", name)
+		vb.sendMessage(update, "Hmm, I don't know that color space. Try /colorspace again!")
+		return
+	}
+
+	vb.settings.Update(update.CallbackQuery.From.ID, func(settings *UserSettings) {
+		settings.ColorSpace = space
+	})
+
+	logger.WithField("color_space", name).Info("Color space selected")
+	vb.sendMessage(update, "Got it! Your next avatar will mix its colors in "+name+".")
+}
+
+func (vb *VacatoBot) handleBlendMenu(update tgbotapi.Update) {
+	vb.sendChoiceMenu(update, "Pick how the colors mix with your photo.", blendCallbackPrefix, blendModeNames)
+}
+
+func (vb *VacatoBot) handleBlendChoice(update tgbotapi.Update, name string) {
+	logger := vb.getUpdateLogger(update)
+
+	mode, ok := blendModes[name]
+	if !ok {
+		logger.Errorf("Unknown blend mode %s", name)
+		vb.sendMessage(update, "Hmm, I don't know that blend mode. Try /blend again!")
+		return
+	}
+
+	vb.settings.Update(update.CallbackQuery.From.ID, func(settings *UserSettings) {
+		settings.BlendMode = mode
+	})
+
+	logger.WithField("blend_mode", name).Info("Blend mode selected")
+	vb.sendMessage(update, "Got it! Your next avatar will use the "+name+" blend mode.")
+}
+
+func (vb *VacatoBot) handleStyleMenu(update tgbotapi.Update) {
+	vb.sendChoiceMenu(update, "Pick how your text should look.", styleCallbackPrefix, textStyleNames)
+}
+
+func (vb *VacatoBot) handleStyleChoice(update tgbotapi.Update, name string) {
+	logger := vb.getUpdateLogger(update)
+
+	style, ok := textStyles[name]
+	if !ok {
+		logger.Errorf("Unknown text style %s", name)
+		vb.sendMessage(update, "Hmm, I don't know that text style. Try /style again!")
+		return
+	}
+
+	vb.settings.Update(update.CallbackQuery.From.ID, func(settings *UserSettings) {
+		settings.TextStyle = style
+	})
+
+	logger.WithField("text_style", name).Info("Text style selected")
+	vb.sendMessage(update, "Got it! Your next avatar will use the "+name+" text style.")
+}
+
+func (vb *VacatoBot) handlePositionMenu(update tgbotapi.Update) {
+	vb.sendChoiceMenu(update, "Where should the text go? Auto keeps it off your face, banners put it on a strip across the bottom.", positionCallbackPrefix, textPositionNames)
+}
+
+func (vb *VacatoBot) handlePositionChoice(update tgbotapi.Update, name string) {
+	logger := vb.getUpdateLogger(update)
+
+	position, ok := textPositions[name]
+	if !ok {
+		logger.Errorf("Unknown text position %s", name)
+		vb.sendMessage(update, "Hmm, I don't know that position. Try /position again!")
+		return
+	}
+
+	vb.settings.Update(update.CallbackQuery.From.ID, func(settings *UserSettings) {
+		settings.TextPosition = position
+	})
+
+	logger.WithField("text_position", name).Info("Text position selected")
+	vb.sendMessage(update, "Got it! Your next avatar will have its text at the "+name+" position.")
+}
+
+// handleFontMenu sends a preview of every font with the buttons to pick one,
+// since buttons themselves can't show a font.
+func (vb *VacatoBot) handleFontMenu(update tgbotapi.Update) {
+	logger := vb.getUpdateLogger(update)
+	logger.WithField("menu", fontCallbackPrefix).Info("Displaying font menu")
+
+	preview, err := RenderFontPreview()
+	if err != nil {
+		logger.WithError(err).Error("Failed to render font preview")
+		vb.sendChoiceMenu(update, "Pick the font for your text.", fontCallbackPrefix, fontFamilyNames)
+		return
+	}
+
+	var buf bytes.Buffer
+	err = vb.encoder.Encode(&buf, preview)
+	if err != nil {
+		logger.WithError(err).Error("Failed to encode font preview")
+		vb.sendChoiceMenu(update, "Pick the font for your text.", fontCallbackPrefix, fontFamilyNames)
+		return
+	}
+
+	_, err = vb.bot.Send(NewImageMessage(getUpdateChatId(update), preview, tgbotapi.FileBytes{
+		Name:  "fonts.png",
+		Bytes: buf.Bytes(),
+	}, "Pick the font for your text.", choiceKeyboard(fontCallbackPrefix, fontFamilyNames)))
+	if err != nil {
+		logger.WithError(err).Error("Failed to send font menu")
+	}
+}
+
+func (vb *VacatoBot) handleFontChoice(update tgbotapi.Update, name string) {
+	logger := vb.getUpdateLogger(update)
+
+	if _, ok := fontFamilies[name]; !ok {
+		logger.Errorf("Unknown font family %s", name)
+		vb.sendMessage(update, "Hmm, I don't know that font. Try /font again!")
+		return
+	}
+
+	vb.settings.Update(update.CallbackQuery.From.ID, func(settings *UserSettings) {
+		settings.FontFamily = name
+	})
+
+	logger.WithField("font_family", name).Info("Font family selected")
+	vb.sendMessage(update, "Got it! Your next avatar will use the "+name+" font.")
+}
+
+func (vb *VacatoBot) handleOutputMenu(update tgbotapi.Update) {
+	vb.sendChoiceMenu(update, "Pick the shape of your avatar. Round ones are ready to use as a profile picture or sticker.", outputCallbackPrefix, outputShapeNames)
+}
+
+func (vb *VacatoBot) handleOutputChoice(update tgbotapi.Update, name string) {
+	logger := vb.getUpdateLogger(update)
+
+	shape, ok := outputShapes[name]
+	if !ok {
+		logger.Errorf("Unknown output shape %s", name)
+		vb.sendMessage(update, "Hmm, I don't know that shape. Try /output again!")
+		return
+	}
+
+	vb.settings.Update(update.CallbackQuery.From.ID, func(settings *UserSettings) {
+		settings.OutputShape = shape
+	})
+
+	logger.WithField("output_shape", name).Info("Output shape selected")
+	vb.sendMessage(update, "Got it! Your next avatar will be "+name+" shaped.")
+}
+
+func (vb *VacatoBot) handleBadgeMenu(update tgbotapi.Update) {
+	vb.sendChoiceMenu(update, "Pick a badge to run along the edge of your avatar.", badgeCallbackPrefix, ringBadgeNames)
+}
+
+func (vb *VacatoBot) handleBadgeChoice(update tgbotapi.Update, name string) {
+	logger := vb.getUpdateLogger(update)
+
+	badge, ok := ringBadges[name]
+	if !ok {
+		logger.Errorf("Unknown ring badge %s", name)
+		vb.sendMessage(update, "Hmm, I don't know that badge. Try /badge again!")
+		return
+	}
+
+	vb.settings.Update(update.CallbackQuery.From.ID, func(settings *UserSettings) {
+		settings.RingBadge = badge
+	})
+
+	logger.WithField("ring_badge", name).Info("Ring badge selected")
+	if badge.Text == "" {
+		vb.sendMessage(update, "Got it! Your next avatar won't have a badge.")
+		return
+	}
+	vb.sendMessage(update, "Got it! Your next avatar will have the "+badge.Text+" badge.")
+}
+
+func (vb *VacatoBot) handleTemplateMenu(update tgbotapi.Update) {
+	vb.sendChoiceMenu(update, templateMenuText(), templateCallbackPrefix, renderTemplateNames)
+}
+
+func (vb *VacatoBot) handleTemplateChoice(update tgbotapi.Update, name string) {
+	logger := vb.getUpdateLogger(update)
+
+	if _, ok := renderTemplates[name]; !ok {
+		logger.Errorf("Unknown template %s", name)
+		vb.sendMessage(update, "Hmm, I don't know that look. Try /template again!")
+		return
+	}
+
+	vb.settings.Update(update.CallbackQuery.From.ID, func(settings *UserSettings) {
+		settings.Template = name
+	})
+
+	logger.WithField("template", name).Info("Template selected")
+	vb.sendMessage(update, "Got it! Your next avatar will use the "+name+" look.")
+}
+
+func (vb *VacatoBot) handleAnimateMenu(update tgbotapi.Update) {
+	vb.sendChoiceMenu(update, "Want your avatar to move? Hue cycles the gradient colors, pulse makes the ring breathe and typewriter types your text out. Pick none for a still picture.", animateCallbackPrefix, animationEffectNames)
+}
+
+func (vb *VacatoBot) handleAnimateChoice(update tgbotapi.Update, name string) {
+	logger := vb.getUpdateLogger(update)
+
+	effect, ok := animationEffects[name]
+	if !ok {
+		logger.Errorf("Unknown animation %s", name)
+		vb.sendMessage(update, "Hmm, I don't know that animation. Try /animate again!")
+		return
+	}
+
+	vb.settings.Update(update.CallbackQuery.From.ID, func(settings *UserSettings) {
+		settings.Animation = effect
+	})
+
+	logger.WithField("animation", name).Info("Animation selected")
+	if effect == AnimationNone {
+		vb.sendMessage(update, "Got it! Your next avatar will be a still picture.")
+		return
+	}
+	vb.sendMessage(update, "Got it! Your next avatar will be animated with the "+name+" effect.")
+}
+
+func (vb *VacatoBot) handleTimezoneCommand(update tgbotapi.Update) {
+	if name := strings.TrimSpace(update.Message.CommandArguments()); name != "" {
+		vb.setTimezone(update, update.Message.From.ID, name)
+		return
+	}
+	vb.sendChoiceMenu(update, "Pick your timezone, I read dates like \"back on Monday\" in it. Not listed? Send /timezone with its name, like /timezone Asia/Yerevan.", timezoneCallbackPrefix, timezoneNames)
+}
+
+func (vb *VacatoBot) handleTimezoneChoice(update tgbotapi.Update, name string) {
+	vb.setTimezone(update, update.CallbackQuery.From.ID, name)
+}
+
+func (vb *VacatoBot) setTimezone(update tgbotapi.Update, userId int64, name string) {
+	logger := vb.getUpdateLogger(update)
+
+	if _, err := time.LoadLocation(name); err != nil || name == "Local" {
+		logger.Errorf("Unknown timezone %s", name)
+		vb.sendMessage(update, "Hmm, I don't know that timezone. Try a name like Europe/Berlin, or pick one from /timezone!")
+		return
+	}
+
+	vb.settings.Update(userId, func(settings *UserSettings) {
+		settings.Timezone = name
+	})
+
+	logger.WithField("timezone", name).Info("Timezone selected")
+	vb.sendMessage(update, "Got it! I'll read your dates in "+name+" time.")
+}
+
+// sendReturnConfirmation shows the user how their text was read and lets
+// them pick the avatar text: the date, the days left or their own words.
+func (vb *VacatoBot) sendReturnConfirmation(update tgbotapi.Update, period AwayPeriod, timezone string) {
+	logger := vb.getUpdateLogger(update)
+	now := time.Now()
+
+	text := "I read that as back on " + returnSummary(period, now) + " (" + timezone + " time).\n" +
+		"What should your avatar say? Wrong timezone? Set yours with /timezone and send your text again."
+
+	msg := tgbotapi.NewMessage(getUpdateChatId(update), text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(ReturnDateText(period), returnCallbackPrefix+"date")),
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(ReturnDaysText(period, now), returnCallbackPrefix+"days")),
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData("Keep my text", returnCallbackPrefix+"keep")),
+	)
+
+	_, err := vb.bot.Send(msg)
+	if err != nil {
+		logger.WithError(err).Error("Failed to send return date confirmation")
+	}
+}
+
+func (vb *VacatoBot) handleReturnChoice(update tgbotapi.Update, choice string) {
+	logger := vb.getUpdateLogger(update)
+	settings := vb.settings.Get(update.CallbackQuery.From.ID)
+
+	if settings.PendingText == "" {
+		vb.sendMessage(update, "Hmm, I lost track of your text. Send it again, please!")
+		return
+	}
+
+	var text string
+	switch choice {
+	case "date":
+		text = ReturnDateText(settings.Away)
+	case "days":
+		text = returnDaysTemplate
+	case "keep":
+		text = settings.PendingText
+	default:
+		logger.Errorf("Unknown return choice %s", choice)
+		vb.sendMessage(update, "Hmm, I don't know that option. Send your text again, please!")
+		return
+	}
+
+	logger.WithFields(logrus.Fields{"choice": choice, "text": text}).Info("Return text selected")
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.WithField("error", r).Error("Panic in renderAvatar")
+			}
+		}()
+
+		err := vb.renderAvatar(update, text)
+		if err != nil {
+			vb.sendMessage(update, "Oh no! Something went wrong. Try again, please!\n\n"+err.Error())
+		}
+	}()
+}
+
+func (vb *VacatoBot) handleRefresh(update tgbotapi.Update) {
+	logger := vb.getUpdateLogger(update)
+	settings := vb.settings.Get(update.Message.From.ID)
+
+	if settings.TextTemplate == "" {
+		vb.sendMessage(update, "There's nothing to refresh yet. Send me your text first!")
+		return
+	}
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.WithField("error", r).Error("Panic in renderAvatar")
+			}
+		}()
+
+		err := vb.renderAvatar(update, settings.TextTemplate)
+		if err != nil {
+			vb.sendMessage(update, "Oh no! Something went wrong. Try again, please!\n\n"+err.Error())
+		}
+	}()
+}
+
 func (vb *VacatoBot) handleCommand(update tgbotapi.Update) {
 	command := update.Message.Command()
 	logger := vb.getUpdateLogger(update)
@@ -135,6 +643,45 @@ func (vb *VacatoBot) handleCommand(update tgbotapi.Update) {
 	case "avatar":
 		vb.sendMessage(update, requestTextMsg)
 
+	case "gradient":
+		vb.handleGradientMenu(update)
+
+	case "palette":
+		vb.handlePaletteMenu(update)
+
+	case "colorspace":
+		vb.handleColorSpaceMenu(update)
+
+	case "blend":
+		vb.handleBlendMenu(update)
+
+	case "style":
+		vb.handleStyleMenu(update)
+
+	case "position":
+		vb.handlePositionMenu(update)
+
+	case "font":
+		vb.handleFontMenu(update)
+
+	case "output":
+		vb.handleOutputMenu(update)
+
+	case "badge":
+		vb.handleBadgeMenu(update)
+
+	case "template":
+		vb.handleTemplateMenu(update)
+
+	case "animate":
+		vb.handleAnimateMenu(update)
+
+	case "timezone":
+		vb.handleTimezoneCommand(update)
+
+	case "refresh":
+		vb.handleRefresh(update)
+
 	default:
 		logger.Errorf("Unknown command %s", command)
 		vb.sendMessage(update, "Oops! I don't recognize that command. Try something else!")
@@ -145,8 +692,50 @@ func (vb *VacatoBot) handleCallback(update tgbotapi.Update) {
 	logger := vb.getUpdateLogger(update)
 	logger.WithField("callback_data", update.CallbackQuery.Data).Info("Received callback query")
 
-	if update.CallbackQuery.Data == "request_text" {
+	data := update.CallbackQuery.Data
+
+	switch {
+	case data == "request_text":
 		vb.sendMessage(update, requestTextMsg)
+
+	case strings.HasPrefix(data, gradientCallbackPrefix):
+		vb.handleGradientChoice(update, strings.TrimPrefix(data, gradientCallbackPrefix))
+
+	case strings.HasPrefix(data, paletteCallbackPrefix):
+		vb.handlePaletteChoice(update, strings.TrimPrefix(data, paletteCallbackPrefix))
+
+	case strings.HasPrefix(data, colorSpaceCallbackPrefix):
+		vb.handleColorSpaceChoice(update, strings.TrimPrefix(data, colorSpaceCallbackPrefix))
+
+	case strings.HasPrefix(data, blendCallbackPrefix):
+		vb.handleBlendChoice(update, strings.TrimPrefix(data, blendCallbackPrefix))
+
+	case strings.HasPrefix(data, styleCallbackPrefix):
+		vb.handleStyleChoice(update, strings.TrimPrefix(data, styleCallbackPrefix))
+
+	case strings.HasPrefix(data, positionCallbackPrefix):
+		vb.handlePositionChoice(update, strings.TrimPrefix(data, positionCallbackPrefix))
+
+	case strings.HasPrefix(data, fontCallbackPrefix):
+		vb.handleFontChoice(update, strings.TrimPrefix(data, fontCallbackPrefix))
+
+	case strings.HasPrefix(data, outputCallbackPrefix):
+		vb.handleOutputChoice(update, strings.TrimPrefix(data, outputCallbackPrefix))
+
+	case strings.HasPrefix(data, badgeCallbackPrefix):
+		vb.handleBadgeChoice(update, strings.TrimPrefix(data, badgeCallbackPrefix))
+
+	case strings.HasPrefix(data, templateCallbackPrefix):
+		vb.handleTemplateChoice(update, strings.TrimPrefix(data, templateCallbackPrefix))
+
+	case strings.HasPrefix(data, animateCallbackPrefix):
+		vb.handleAnimateChoice(update, strings.TrimPrefix(data, animateCallbackPrefix))
+
+	case strings.HasPrefix(data, timezoneCallbackPrefix):
+		vb.handleTimezoneChoice(update, strings.TrimPrefix(data, timezoneCallbackPrefix))
+
+	case strings.HasPrefix(data, returnCallbackPrefix):
+		vb.handleReturnChoice(update, strings.TrimPrefix(data, returnCallbackPrefix))
 	}
 }
 
@@ -229,6 +818,31 @@ func NewVacatoBot() VacatoBot {
 		logger.SetLevel(logrus.InfoLevel)
 	}
 
+	if assetsDir := os.Getenv("ASSETS_DIR"); assetsDir != "" {
+		discovered, err := UseAssetOverrideDir(assetsDir)
+		if err != nil {
+			logger.WithError(err).Fatal("Failed to load assets override directory")
+		}
+		logger.WithFields(logrus.Fields{
+			"assets_dir":    assetsDir,
+			"font_families": discovered,
+		}).Info("Using assets override directory")
+	}
+
+	signature, err := LoadSignatureConfig(os.Getenv)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to configure the signature")
+	}
+	signatureConfig = signature
+
+	if err := LoadTemplates(); err != nil {
+		logger.WithError(err).Fatal("Failed to load templates")
+	}
+	logger.WithFields(logrus.Fields{
+		"templates": renderTemplateNames,
+		"badges":    ringBadgeNames,
+	}).Info("Loaded templates")
+
 	bot, err := GetBot(token, isDebug)
 	if err != nil {
 		logger.WithError(err).Fatal("Failed to initialize bot")
@@ -236,5 +850,5 @@ func NewVacatoBot() VacatoBot {
 
 	encoder := png.Encoder{}
 
-	return VacatoBot{bot: bot, logger: logger, encoder: encoder}
+	return VacatoBot{bot: bot, logger: logger, encoder: encoder, settings: NewSettingsStore()}
 }
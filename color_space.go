@@ -0,0 +1,170 @@
+package main
+
+import (
+	"image/color"
+	"math"
+)
+
+// ColorSpace selects where two colors are interpolated. ColorSpaceSRGB lerps
+// the gamma-encoded bytes directly, the others give perceptually even
+// transitions without the grey midpoints between complementary colors.
+type ColorSpace uint8
+
+const (
+	ColorSpaceSRGB ColorSpace = iota
+	ColorSpaceLinearRGB
+	ColorSpaceOKLab
+	ColorSpaceOKLCH
+)
+
+var colorSpaces = map[string]ColorSpace{
+	"oklab":  ColorSpaceOKLab,
+	"oklch":  ColorSpaceOKLCH,
+	"linear": ColorSpaceLinearRGB,
+	"srgb":   ColorSpaceSRGB,
+}
+
+var colorSpaceNames = []string{"oklab", "oklch", "linear", "srgb"}
+
+const defaultColorSpace = "oklab"
+
+type OKLab struct {
+	L, A, B float64
+}
+
+var srgbToLinearTable = func() [256]float64 {
+	var table [256]float64
+	for i := range table {
+		table[i] = srgbToLinear(float64(i) / 255)
+	}
+	return table
+}()
+
+func srgbToLinear(value float64) float64 {
+	if value <= 0.04045 {
+		return value / 12.92
+	}
+	return math.Pow((value+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(value float64) float64 {
+	if value <= 0.0031308 {
+		return value * 12.92
+	}
+	return 1.055*math.Pow(value, 1/2.4) - 0.055
+}
+
+func linearToByte(value float64) uint8 {
+	return uint8(math.Round(clampUnit(linearToSRGB(clampUnit(value))) * 255))
+}
+
+func linearRGBToOKLab(r, g, b float64) OKLab {
+	l := math.Cbrt(0.4122214708*r + 0.5363325363*g + 0.0514459929*b)
+	m := math.Cbrt(0.2119034982*r + 0.6806995451*g + 0.1073969566*b)
+	s := math.Cbrt(0.0883024619*r + 0.2817188376*g + 0.6299787005*b)
+
+	return OKLab{
+		L: 0.2104542553*l + 0.7936177850*m - 0.0040720468*s,
+		A: 1.9779984951*l - 2.4285922050*m + 0.4505937099*s,
+		B: 0.0259040371*l + 0.7827717662*m - 0.8086757660*s,
+	}
+}
+
+func (c OKLab) linearRGB() (float64, float64, float64) {
+	l := c.L + 0.3963377774*c.A + 0.2158037573*c.B
+	m := c.L - 0.1055613458*c.A - 0.0638541728*c.B
+	s := c.L - 0.0894841775*c.A - 1.2914855480*c.B
+
+	l, m, s = l*l*l, m*m*m, s*s*s
+
+	return 4.0767416621*l - 3.3077115913*m + 0.2309699292*s,
+		-1.2684380046*l + 2.6097574011*m - 0.3413193965*s,
+		-0.0041960863*l - 0.7034186147*m + 1.7076147010*s
+}
+
+func ColorToOKLab(c color.NRGBA) OKLab {
+	return linearRGBToOKLab(srgbToLinearTable[c.R], srgbToLinearTable[c.G], srgbToLinearTable[c.B])
+}
+
+func OKLabToColor(c OKLab, alpha uint8) color.NRGBA {
+	r, g, b := c.linearRGB()
+	return color.NRGBA{R: linearToByte(r), G: linearToByte(g), B: linearToByte(b), A: alpha}
+}
+
+// InterpolateColors mixes colorA and colorB in the given space, alpha is
+// always interpolated linearly.
+func InterpolateColors(colorA, colorB color.NRGBA, t float64, space ColorSpace) color.NRGBA {
+	switch space {
+	case ColorSpaceLinearRGB:
+		return color.NRGBA{
+			R: linearToByte(lerp(srgbToLinearTable[colorA.R], srgbToLinearTable[colorB.R], t)),
+			G: linearToByte(lerp(srgbToLinearTable[colorA.G], srgbToLinearTable[colorB.G], t)),
+			B: linearToByte(lerp(srgbToLinearTable[colorA.B], srgbToLinearTable[colorB.B], t)),
+			A: blendChannel(colorA.A, colorB.A, t),
+		}
+
+	case ColorSpaceOKLab:
+		labA, labB := ColorToOKLab(colorA), ColorToOKLab(colorB)
+		mixed := OKLab{
+			L: lerp(labA.L, labB.L, t),
+			A: lerp(labA.A, labB.A, t),
+			B: lerp(labA.B, labB.B, t),
+		}
+		return OKLabToColor(mixed, blendChannel(colorA.A, colorB.A, t))
+
+	case ColorSpaceOKLCH:
+		return OKLabToColor(interpolateOKLCH(ColorToOKLab(colorA), ColorToOKLab(colorB), t), blendChannel(colorA.A, colorB.A, t))
+
+	default:
+		return BlendColors(colorA, colorB, t)
+	}
+}
+
+// achromaticChroma is the chroma below which a color is treated as grey and
+// its hue is ignored, otherwise fading to white would swing through the
+// meaningless hue of the grey.
+const achromaticChroma = 1e-4
+
+func interpolateOKLCH(labA, labB OKLab, t float64) OKLab {
+	chromaA, chromaB := math.Hypot(labA.A, labA.B), math.Hypot(labB.A, labB.B)
+	hueA, hueB := math.Atan2(labA.B, labA.A), math.Atan2(labB.B, labB.A)
+
+	if chromaA < achromaticChroma {
+		hueA = hueB
+	}
+	if chromaB < achromaticChroma {
+		hueB = hueA
+	}
+
+	delta := hueB - hueA
+	if delta > math.Pi {
+		delta -= 2 * math.Pi
+	} else if delta < -math.Pi {
+		delta += 2 * math.Pi
+	}
+
+	chroma := lerp(chromaA, chromaB, t)
+	hue := hueA + delta*t
+
+	return OKLab{
+		L: lerp(labA.L, labB.L, t),
+		A: chroma * math.Cos(hue),
+		B: chroma * math.Sin(hue),
+	}
+}
+
+func lerp(a, b, t float64) float64 {
+	return a + (b-a)*t
+}
+
+// RotateHue turns the hue of c by degrees in OKLCH, keeping its lightness,
+// chroma and alpha. Greys have no hue and are returned unchanged.
+func RotateHue(c color.NRGBA, degrees float64) color.NRGBA {
+	lab := ColorToOKLab(c)
+	if math.Hypot(lab.A, lab.B) < achromaticChroma {
+		return c
+	}
+
+	sin, cos := math.Sincos(degrees * math.Pi / 180)
+	return OKLabToColor(OKLab{L: lab.L, A: lab.A*cos - lab.B*sin, B: lab.A*sin + lab.B*cos}, c.A)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestColorToOKLab(t *testing.T) {
+	// Reference values from Björn Ottosson's OKLab definition.
+	tests := []struct {
+		name     string
+		color    color.NRGBA
+		expected OKLab
+	}{
+		{name: "White", color: color.NRGBA{255, 255, 255, 255}, expected: OKLab{L: 1, A: 0, B: 0}},
+		{name: "Red", color: color.NRGBA{255, 0, 0, 255}, expected: OKLab{L: 0.627955, A: 0.224863, B: 0.125846}},
+		{name: "Green", color: color.NRGBA{0, 255, 0, 255}, expected: OKLab{L: 0.866440, A: -0.233888, B: 0.179498}},
+		{name: "Blue", color: color.NRGBA{0, 0, 255, 255}, expected: OKLab{L: 0.452014, A: -0.032457, B: -0.311528}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := ColorToOKLab(tt.color)
+			if math.Abs(actual.L-tt.expected.L) > 1e-4 ||
+				math.Abs(actual.A-tt.expected.A) > 1e-4 ||
+				math.Abs(actual.B-tt.expected.B) > 1e-4 {
+				t.Errorf("Expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestOKLabRoundTrip(t *testing.T) {
+	for value := 0; value < 256; value += 5 {
+		original := color.NRGBA{uint8(value), uint8(255 - value), uint8(value / 2), 255}
+		actual := OKLabToColor(ColorToOKLab(original), 255)
+		if actual != original {
+			t.Errorf("Expected %v after round trip, got %v", original, actual)
+		}
+	}
+}
+
+func TestInterpolateColorsMidpoint(t *testing.T) {
+	black := color.NRGBA{0, 0, 0, 255}
+	white := color.NRGBA{255, 255, 255, 255}
+	red := color.NRGBA{255, 0, 0, 255}
+	blue := color.NRGBA{0, 0, 255, 255}
+	yellow := color.NRGBA{255, 255, 0, 255}
+
+	tests := []struct {
+		name     string
+		colorA   color.NRGBA
+		colorB   color.NRGBA
+		space    ColorSpace
+		expected color.NRGBA
+	}{
+		{name: "sRGB black to white", colorA: black, colorB: white, space: ColorSpaceSRGB, expected: color.NRGBA{127, 127, 127, 255}},
+		{name: "Linear RGB black to white", colorA: black, colorB: white, space: ColorSpaceLinearRGB, expected: color.NRGBA{188, 188, 188, 255}},
+		{name: "OKLab black to white", colorA: black, colorB: white, space: ColorSpaceOKLab, expected: color.NRGBA{99, 99, 99, 255}},
+		{name: "OKLCH black to white ignores hue", colorA: black, colorB: white, space: ColorSpaceOKLCH, expected: color.NRGBA{99, 99, 99, 255}},
+		{name: "OKLab red to blue", colorA: red, colorB: blue, space: ColorSpaceOKLab, expected: color.NRGBA{140, 83, 162, 255}},
+		{name: "OKLab yellow to blue avoids grey", colorA: yellow, colorB: blue, space: ColorSpaceOKLab, expected: color.NRGBA{108, 171, 199, 255}},
+		{
+			name:     "Alpha is interpolated linearly",
+			colorA:   color.NRGBA{255, 0, 0, 0},
+			colorB:   color.NRGBA{255, 0, 0, 255},
+			space:    ColorSpaceOKLab,
+			expected: color.NRGBA{255, 0, 0, 127},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := InterpolateColors(tt.colorA, tt.colorB, 0.5, tt.space)
+			if actual != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestInterpolateColorsEndpoints(t *testing.T) {
+	colorA := color.NRGBA{12, 200, 99, 255}
+	colorB := color.NRGBA{250, 30, 170, 255}
+
+	for _, space := range []ColorSpace{ColorSpaceSRGB, ColorSpaceLinearRGB, ColorSpaceOKLab, ColorSpaceOKLCH} {
+		if actual := InterpolateColors(colorA, colorB, 0, space); actual != colorA {
+			t.Errorf("Space %d: expected %v at start, got %v", space, colorA, actual)
+		}
+		if actual := InterpolateColors(colorA, colorB, 1, space); actual != colorB {
+			t.Errorf("Space %d: expected %v at end, got %v", space, colorB, actual)
+		}
+	}
+}
+
+func TestRotateHue(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    color.NRGBA
+		degrees  float64
+		expected color.NRGBA
+	}{
+		{name: "No rotation", input: color.NRGBA{12, 200, 99, 255}, degrees: 0, expected: color.NRGBA{12, 200, 99, 255}},
+		{name: "Full turn", input: color.NRGBA{12, 200, 99, 128}, degrees: 360, expected: color.NRGBA{12, 200, 99, 128}},
+		{name: "Grey is kept", input: color.NRGBA{128, 128, 128, 255}, degrees: 90, expected: color.NRGBA{128, 128, 128, 255}},
+		{name: "Half turn of blue", input: color.NRGBA{0, 0, 255, 255}, degrees: 180, expected: color.NRGBA{160, 32, 0, 255}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := RotateHue(tt.input, tt.degrees); actual != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
@@ -1,5 +1,22 @@
 package main
 
 const requestTextMsg = "What would you like to add to your avatar?\n" +
-	"You can enter up to two lines, like 'On vacation!' or just 'Day off!'\n" +
+	"Something short like 'On vacation!' or just 'Day off!' works best, longer text is wrapped for you\n" +
+	"Add {name} or {days_left} to have them filled in, /refresh updates them later\n" +
 	"Please reply directly to this message with your text!"
+
+const (
+	gradientCallbackPrefix   = "gradient:"
+	paletteCallbackPrefix    = "palette:"
+	colorSpaceCallbackPrefix = "colorspace:"
+	blendCallbackPrefix      = "blend:"
+	styleCallbackPrefix      = "style:"
+	positionCallbackPrefix   = "position:"
+	fontCallbackPrefix       = "font:"
+	outputCallbackPrefix     = "output:"
+	badgeCallbackPrefix      = "badge:"
+	templateCallbackPrefix   = "template:"
+	animateCallbackPrefix    = "animate:"
+	timezoneCallbackPrefix   = "timezone:"
+	returnCallbackPrefix     = "return:"
+)
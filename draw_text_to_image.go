@@ -1,109 +1,161 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"image"
 	"image/color"
+	"io/fs"
 	"math"
-	"os"
+	"slices"
 	"strings"
+	"sync"
 
+	"github.com/go-text/typesetting/di"
+	gotextfont "github.com/go-text/typesetting/font"
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/opentype"
 	"golang.org/x/image/font/sfnt"
 	"golang.org/x/image/math/fixed"
 )
 
-var fontCache = map[string]*FontCache{}
+var (
+	fontCache   = map[string]*FontCache{}
+	fontCacheMu sync.Mutex
+)
 
+// FontCache holds an ordered fallback chain of fonts. The first font is the
+// primary one, the others are only used for runes it has no glyph for.
+// shapingFonts are the same fonts parsed for the shaper, which needs the
+// OpenType layout tables.
 type FontCache struct {
-	font          *sfnt.Font
-	defaultFace   font.Face
-	signatureFace font.Face
+	fonts        []*sfnt.Font
+	shapingFonts []*gotextfont.Font
+	defaultFace  font.Face
+}
+
+// fallbackFontPaths cover scripts the font families lack: DejaVu Sans has
+// Georgian, Armenian, Arabic, Hebrew and N'Ko, the Noto fonts have
+// Devanagari, Tamil, Kannada, Thai and Myanmar, and WenQuanYi Micro Hei has
+// simplified and traditional Chinese, Japanese and Korean. Fonts dropped into
+// fallbackFontDir are tried after these in name order.
+var fallbackFontPaths = []string{
+	"DejaVuSans.ttf",
+	"NotoSansDevanagari-Regular.ttf",
+	"NotoSansTamil-Regular.ttf",
+	"NotoSansKannada-Regular.ttf",
+	"NotoSansThai-Regular.ttf",
+	"NotoSansMyanmar-Regular.ttf",
+	"wqy-microhei.ttf",
+}
+
+var fallbackFontDir = "fonts/fallback"
+
+// fontPathsFor returns the fallback chain with primaryPath first.
+func fontPathsFor(primaryPath string) []string {
+	paths := append([]string{primaryPath}, fallbackFontPaths...)
+	return append(paths, assetFiles(fallbackFontDir, fontFilePatterns...)...)
+}
+
+func CachedLoadDefaultFont() (*FontCache, error) {
+	return CachedLoadFontFamily(defaultFontFamily)
 }
 
-func CachedLoadFont(fontPath string) (*FontCache, error) {
-	if cacheValue, cacheExists := fontCache[fontPath]; cacheExists {
+func CachedLoadFont(fontPaths ...string) (*FontCache, error) {
+	fontCacheMu.Lock()
+	defer fontCacheMu.Unlock()
+
+	key := strings.Join(fontPaths, "|")
+	if cacheValue, cacheExists := fontCache[key]; cacheExists {
 		return cacheValue, nil
 	}
 
-	ttf, err := LoadFont(fontPath)
+	fc := &FontCache{}
+	for _, fontPath := range fontPaths {
+		ttf, shapingFont, err := LoadFont(fontPath)
 
-	if err != nil {
-		return nil, err
+		if err != nil {
+			return nil, err
+		}
+		fc.fonts = append(fc.fonts, ttf)
+		fc.shapingFonts = append(fc.shapingFonts, shapingFont)
 	}
 
-	defaultFace, err := opentype.NewFace(ttf, &opentype.FaceOptions{
-		Size:    48,
-		DPI:     72,
-		Hinting: font.HintingNone,
-	})
+	defaultFace, err := fc.NewFace(baseFontSize)
 
 	if err != nil {
 		return nil, err
 	}
 
-	signatureFace, _ := opentype.NewFace(ttf, &opentype.FaceOptions{
-		Size:    16,
-		DPI:     72,
-		Hinting: font.HintingNone,
-	})
+	fc.defaultFace = defaultFace
+	fontCache[key] = fc
 
-	fontCache[fontPath] = &FontCache{
-		font:          ttf,
-		defaultFace:   defaultFace,
-		signatureFace: signatureFace,
+	return fc, nil
+}
+
+// NewFace returns a face of the given size over the whole fallback chain.
+func (fc *FontCache) NewFace(size float64) (font.Face, error) {
+	faces := make([]font.Face, 0, len(fc.fonts))
+	for _, ttf := range fc.fonts {
+		face, err := opentype.NewFace(ttf, &opentype.FaceOptions{
+			Size:    size,
+			DPI:     72,
+			Hinting: font.HintingNone,
+		})
+
+		if err != nil {
+			return nil, err
+		}
+		faces = append(faces, face)
 	}
 
-	return fontCache[fontPath], nil
+	return &fallbackFace{collection: fc, size: size, fonts: fc.fonts, faces: faces}, nil
 }
 
-func LoadFont(fontPath string) (*sfnt.Font, error) {
-	fontBytes, err := os.ReadFile(fontPath)
+// LoadFont reads a font from the assets.
+func LoadFont(fontPath string) (*sfnt.Font, *gotextfont.Font, error) {
+	fontBytes, err := fs.ReadFile(assets, fontPath)
 	if err != nil {
-		return nil, fmt.Errorf("error reading font: %v", err)
+		return nil, nil, fmt.Errorf("error reading font: %v", err)
 	}
 
 	ttfFont, err := opentype.Parse(fontBytes)
 
 	if err != nil {
-		return nil, fmt.Errorf("error parsing font: %v", err)
+		return nil, nil, fmt.Errorf("error parsing font: %v", err)
 	}
 
-	return ttfFont, nil
-}
-
-func DrawTextToImage(img *image.NRGBA, text string) error {
-	bounds := img.Bounds()
+	shapingFace, err := gotextfont.ParseTTF(bytes.NewReader(fontBytes))
 
-	ttfFont, err := CachedLoadFont("./assets/Roboto-Regular.ttf")
 	if err != nil {
-		return err
+		return nil, nil, fmt.Errorf("error parsing font: %v", err)
 	}
 
-	const fontSize = 48
-	const horizontalPaddingPercent = 10.0
-	const verticalPaddingPercent = 10.0
+	return ttfFont, shapingFace.Font, nil
+}
 
-	horizontalPadding := float64(bounds.Dx()) * horizontalPaddingPercent / 100.0
-	verticalPadding := float64(bounds.Dy()) * verticalPaddingPercent / 100.0
+// DrawTextToImage lays text out to fit img and draws it centered in the given
+// font family. img may be a sub-image to draw text into part of a picture.
+// The returned layout tells which lines were drawn and whether the text had
+// to be shortened.
+func DrawTextToImage(img *image.NRGBA, text, fontFamily string, style TextStyle, options TextLayoutOptions) (TextLayout, error) {
+	bounds := img.Bounds()
 
-	lines := strings.Split(text, "\n")
-	if len(lines) > 2 {
-		lines = lines[:2]
+	ttfFont, err := CachedLoadFontFamily(fontFamily)
+	if err != nil {
+		return TextLayout{}, err
 	}
 
-	textWidth, textHeight := measureMultilineTextSize(ttfFont.defaultFace, lines)
-	scaleFactor := calculateScaleFactor(textWidth, textHeight, float64(bounds.Dx()), float64(bounds.Dy()), horizontalPadding, verticalPadding)
+	horizontalPadding, verticalPadding := textPadding(bounds)
+
+	layout := LayoutText(ttfFont.defaultFace, text, bounds, options)
+	lines := layout.Lines
 
-	scaledFace, err := opentype.NewFace(ttfFont.font, &opentype.FaceOptions{
-		Size:    fontSize * scaleFactor,
-		DPI:     72,
-		Hinting: font.HintingNone,
-	})
+	scaledFontSize := layout.FontSize
+	scaledFace, err := ttfFont.NewFace(scaledFontSize)
 
 	if err != nil {
-		return fmt.Errorf("error creating scaled font face: %v", err)
+		return TextLayout{}, fmt.Errorf("error creating scaled font face: %v", err)
 	}
 
 	scaledDrawer := &font.Drawer{
@@ -111,46 +163,77 @@ func DrawTextToImage(img *image.NRGBA, text string) error {
 	}
 	defer scaledFace.Close()
 
-	textHeight = measureMultilineTextHeight(scaledFace, len(lines))
+	textHeight := measureMultilineTextHeight(scaledFace, len(lines))
 
-	startY := verticalPadding + ((float64(bounds.Dy()) - 2*verticalPadding - textHeight) / 2) + float64(scaledFace.Metrics().Ascent.Ceil())
+	startY := float64(bounds.Min.Y) + verticalPadding + ((float64(bounds.Dy()) - 2*verticalPadding - textHeight) / 2) + float64(scaledFace.Metrics().Ascent.Ceil())
 
+	// Every line is placed and its colors picked before anything is painted,
+	// so the plate or shadow of one line doesn't count as the background of
+	// the next.
+	metrics := scaledFace.Metrics()
+	texts := slices.Clone(lines)
+	dots := make([]fixed.Point26_6, len(lines))
+	styles := make([]TextStyle, len(lines))
+	var plateRects []image.Rectangle
+	var plates []color.NRGBA
+
+	unrevealed := options.Reveal
 	for i, line := range lines {
 		lineWidth := measureLineWidth(scaledDrawer, line)
-		lineStartX := horizontalPadding + (float64(bounds.Dx())-2*horizontalPadding-lineWidth)/2
-		y := startY + float64(i)*float64(scaledFace.Metrics().Height.Ceil())
-		drawer := &font.Drawer{
-			Dst:  img,
-			Src:  image.NewUniform(color.White),
-			Face: scaledFace,
-			Dot:  fixed.Point26_6{X: fixed.Int26_6(lineStartX * 64), Y: fixed.Int26_6(y * 64)},
+		lineStartX := float64(bounds.Min.X) + horizontalPadding + (float64(bounds.Dx())-2*horizontalPadding-lineWidth)/2
+		y := startY + float64(i)*float64(metrics.Height.Ceil())
+
+		rect := image.Rect(int(lineStartX), int(y)-metrics.Ascent.Ceil(), int(math.Ceil(lineStartX+lineWidth)), int(y)+metrics.Descent.Ceil())
+		var plate color.NRGBA
+		styles[i], plate = contrastStyle(img, rect, style)
+		if plate.A > 0 {
+			plateRects = append(plateRects, rect)
+			plates = append(plates, plate)
 		}
-		drawer.DrawString(line)
-	}
 
-	return nil
-}
+		if options.Reveal > 0 {
+			var typed string
+			typed, unrevealed = typedPrefix(line, unrevealed)
+			if baseDirection(line) == di.DirectionRTL {
+				lineStartX += lineWidth - measureLineWidth(scaledDrawer, typed)
+			}
+			texts[i] = typed
+		}
+		dots[i] = fixed.Point26_6{X: fixed.Int26_6(lineStartX * 64), Y: fixed.Int26_6(y * 64)}
+	}
 
-const signature = "@VacatoBot"
+	drawTextPlates(img, plateRects, plates, scaledFontSize)
 
-func DrawSignature(img *image.NRGBA) error {
-	ttfFont, err := CachedLoadFont("./assets/Roboto-Regular.ttf")
-	if err != nil {
-		return err
+	// Neighbouring lines of the same colors are painted together, as one
+	// block of text.
+	canvas := newTextCanvas(bounds)
+	for i, line := range texts {
+		if i > 0 && styles[i] != styles[i-1] {
+			paintText(img, canvas, styles[i-1], scaledFontSize)
+			canvas = newTextCanvas(bounds)
+		}
+		canvas.drawLine(scaledFace, dots[i], line)
+	}
+	if len(texts) > 0 {
+		paintText(img, canvas, styles[len(texts)-1], scaledFontSize)
 	}
 
+	return layout, nil
+}
+
+var textColor = color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+
+// drawStringMask rasterizes text as coverage into mask. Glyphs are rendered
+// to a mask first and composited with FillMask afterwards, so every text
+// layer goes through the same Porter-Duff path as the rest of the image.
+func drawStringMask(mask *image.Alpha, face font.Face, dot fixed.Point26_6, text string) {
 	drawer := &font.Drawer{
-		Dst:  img,
-		Src:  image.NewUniform(color.White),
-		Face: ttfFont.signatureFace,
-		Dot: fixed.Point26_6{
-			X: fixed.Int26_6(img.Bounds().Dx()*64 - 6000),
-			Y: fixed.Int26_6(img.Bounds().Dy()*64 - 500),
-		},
+		Dst:  mask,
+		Src:  image.Opaque,
+		Face: face,
+		Dot:  dot,
 	}
-	drawer.DrawString(signature)
-
-	return nil
+	drawer.DrawString(text)
 }
 
 func measureMultilineTextSize(face font.Face, lines []string) (float64, float64) {
@@ -173,8 +256,22 @@ func measureMultilineTextHeight(face font.Face, linesCount int) float64 {
 	return float64(face.Metrics().Height.Ceil() * linesCount)
 }
 
+// measureLineWidth includes emoji clusters, which take emojiAdvance each, or
+// the width of their glyphs when there is no bitmap for them. Runs of complex
+// scripts are measured after shaping.
 func measureLineWidth(drawer *font.Drawer, text string) float64 {
-	width := drawer.MeasureString(text)
+	var width fixed.Int26_6
+	for _, run := range splitEmoji(text) {
+		if !run.Emoji || CachedLoadEmoji(run.Text) == nil {
+			if face, ok := drawer.Face.(*fallbackFace); ok && needsShaping(run.Text) {
+				width += face.collection.shapeLine(run.Text, face.size).advance
+				continue
+			}
+			width += drawer.MeasureString(run.Text)
+		} else {
+			width += emojiAdvance(drawer.Face)
+		}
+	}
 	return float64(width) / 64
 }
 
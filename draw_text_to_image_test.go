@@ -35,7 +35,7 @@ func TestDrawTextToImage(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			img := image.NewNRGBA(image.Rect(0, 0, tt.width, tt.height))
-			err := DrawTextToImage(img, tt.text)
+			_, err := DrawTextToImage(img, tt.text, defaultFontFamily, defaultTextStyle, defaultTextLayoutOptions)
 			if err != nil {
 				t.Fatalf("DrawTextToImage failed: %v", err)
 			}
@@ -50,7 +50,7 @@ func TestDrawTextToImage(t *testing.T) {
 
 func TestDrawTextToImageWithReference(t *testing.T) {
 	img := image.NewNRGBA(image.Rect(0, 0, 400, 200))
-	err := DrawTextToImage(img, "Hello World!")
+	_, err := DrawTextToImage(img, "Hello World!", defaultFontFamily, defaultTextStyle, defaultTextLayoutOptions)
 	if err != nil {
 		t.Fatalf("DrawTextToImage failed: %v", err)
 	}
@@ -70,7 +70,7 @@ func BenchmarkDrawTextToImage(b *testing.B) {
 	text := "This is a test text.\nWe will render this text multiple times for benchmarking."
 
 	for i := 0; i < b.N; i++ {
-		err := DrawTextToImage(img, text)
+		_, err := DrawTextToImage(img, text, defaultFontFamily, defaultTextStyle, defaultTextLayoutOptions)
 		if err != nil {
 			b.Fatalf("DrawTextToImage failed: %v", err)
 		}
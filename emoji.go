@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"io/fs"
+	"log"
+	"path"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// emojiDir holds color emoji bitmaps named after their code points in
+// lowercase hex joined with "-", the Twemoji convention (e.g. 1f3d6.png or
+// 1f469-200d-1f4bb.png).
+var emojiDir = "emoji"
+
+type textRun struct {
+	Text  string
+	Emoji bool
+}
+
+const (
+	zeroWidthJoiner    = '\u200d'
+	variationText      = '\ufe0e'
+	variationEmoji     = '\ufe0f'
+	combiningKeycap    = '\u20e3'
+	regionalIndicatorA = '\U0001f1e6'
+	regionalIndicatorZ = '\U0001f1ff'
+	skinToneLight      = '\U0001f3fb'
+	skinToneDark       = '\U0001f3ff'
+	tagSpace           = '\U000e0020'
+	cancelTag          = '\U000e007f'
+)
+
+func isRegionalIndicator(r rune) bool {
+	return r >= regionalIndicatorA && r <= regionalIndicatorZ
+}
+
+func isSkinTone(r rune) bool {
+	return r >= skinToneLight && r <= skinToneDark
+}
+
+func isTag(r rune) bool {
+	return r >= tagSpace && r <= cancelTag
+}
+
+// isEmojiPresentation reports runes that are drawn as emoji by default.
+func isEmojiPresentation(r rune) bool {
+	switch {
+	case r >= 0x1f000 && r <= 0x1faff:
+		return !isRegionalIndicator(r) && !isSkinTone(r)
+	case r >= 0x231a && r <= 0x23ff,
+		r >= 0x2600 && r <= 0x27bf,
+		r >= 0x2b05 && r <= 0x2b55,
+		r == 0x2934, r == 0x2935, r == 0x3030, r == 0x303d, r == 0x3297, r == 0x3299:
+		return true
+	}
+	return false
+}
+
+// isTextDefaultEmoji reports runes that regular fonts draw fine but which
+// become emoji when followed by U+FE0F, like © or ↔.
+func isTextDefaultEmoji(r rune) bool {
+	switch {
+	case r == 0xa9, r == 0xae, r == 0x203c, r == 0x2049, r == 0x2122, r == 0x2139,
+		r >= 0x2194 && r <= 0x21aa, r == 0x24c2, r == 0x25aa, r == 0x25ab, r == 0x25b6,
+		r == 0x25c0, r >= 0x25fb && r <= 0x25fe:
+		return true
+	}
+	return false
+}
+
+func isKeycapBase(r rune) bool {
+	return r >= '0' && r <= '9' || r == '#' || r == '*'
+}
+
+// splitEmoji splits text into plain text runs and emoji clusters. A cluster
+// covers flags, keycaps, tag sequences, skin tone modifiers and ZWJ sequences
+// such as 👩🏽‍💻, so each cluster maps to a single bitmap.
+func splitEmoji(text string) []textRun {
+	var runs []textRun
+	plainStart := 0
+
+	for i := 0; i < len(text); {
+		length := emojiClusterLength(text[i:])
+		if length == 0 {
+			_, size := utf8.DecodeRuneInString(text[i:])
+			i += size
+			continue
+		}
+
+		if plainStart < i {
+			runs = append(runs, textRun{Text: text[plainStart:i]})
+		}
+		runs = append(runs, textRun{Text: text[i : i+length], Emoji: true})
+		i += length
+		plainStart = i
+	}
+
+	if plainStart < len(text) {
+		runs = append(runs, textRun{Text: text[plainStart:]})
+	}
+	return runs
+}
+
+// emojiClusterLength returns the byte length of the emoji cluster at the
+// start of text, or 0 when text doesn't start with an emoji.
+func emojiClusterLength(text string) int {
+	first, size := utf8.DecodeRuneInString(text)
+	next, nextSize := utf8.DecodeRuneInString(text[size:])
+
+	switch {
+	case isRegionalIndicator(first):
+		if isRegionalIndicator(next) {
+			return size + nextSize
+		}
+		return 0
+
+	case isKeycapBase(first):
+		length := size
+		if next == variationEmoji {
+			length += nextSize
+		}
+		if r, s := utf8.DecodeRuneInString(text[length:]); r == combiningKeycap {
+			return length + s
+		}
+		return 0
+
+	case isTextDefaultEmoji(first):
+		if next != variationEmoji {
+			return 0
+		}
+
+	case !isEmojiPresentation(first):
+		return 0
+	}
+
+	if next == variationText {
+		return 0
+	}
+
+	length := size
+	for {
+		length += emojiModifiersLength(text[length:])
+
+		r, s := utf8.DecodeRuneInString(text[length:])
+		if r != zeroWidthJoiner {
+			return length
+		}
+		joined, joinedSize := utf8.DecodeRuneInString(text[length+s:])
+		if !isEmojiPresentation(joined) && !isTextDefaultEmoji(joined) {
+			return length
+		}
+		length += s + joinedSize
+	}
+}
+
+func emojiModifiersLength(text string) int {
+	length := 0
+	for length < len(text) {
+		r, size := utf8.DecodeRuneInString(text[length:])
+		if r != variationEmoji && !isSkinTone(r) && !isTag(r) {
+			break
+		}
+		length += size
+	}
+	return length
+}
+
+// emojiFileNames lists the bitmap names to try for a cluster. Twemoji drops
+// U+FE0F from the name unless the sequence has a ZWJ, so both are tried.
+func emojiFileNames(cluster string) []string {
+	var all, withoutVariation []string
+	for _, r := range cluster {
+		code := fmt.Sprintf("%x", r)
+		all = append(all, code)
+		if r != variationEmoji {
+			withoutVariation = append(withoutVariation, code)
+		}
+	}
+
+	names := []string{strings.Join(all, "-") + ".png"}
+	if len(withoutVariation) != len(all) {
+		names = append(names, strings.Join(withoutVariation, "-")+".png")
+	}
+	return names
+}
+
+var (
+	emojiCache   = map[string]*image.NRGBA{}
+	emojiCacheMu sync.Mutex
+)
+
+// CachedLoadEmoji returns the bitmap for an emoji cluster, or nil when no
+// bitmap is available for it. Only bitmaps found are cached, so the cache is
+// bounded by the emoji assets however many clusters users send. Missing
+// bitmaps are logged and the cluster is drawn with the font's glyphs.
+func CachedLoadEmoji(cluster string) *image.NRGBA {
+	emojiCacheMu.Lock()
+	defer emojiCacheMu.Unlock()
+
+	if cacheValue, cacheExists := emojiCache[cluster]; cacheExists {
+		return cacheValue
+	}
+
+	var bitmap *image.NRGBA
+	for _, name := range emojiFileNames(cluster) {
+		assetPath := path.Join(emojiDir, name)
+		if _, err := fs.Stat(assets, assetPath); err != nil {
+			continue
+		}
+
+		img, err := LoadAssetImage(assetPath)
+		if err == nil {
+			bitmap = img
+			break
+		}
+	}
+
+	if bitmap == nil {
+		log.Printf("No emoji bitmap for %q, drawing it as text", cluster)
+		return nil
+	}
+
+	emojiCache[cluster] = bitmap
+	return bitmap
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"testing"
+)
+
+func TestSplitEmoji(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected []textRun
+	}{
+		{
+			name:     "Plain text is a single run",
+			text:     "On vacation!",
+			expected: []textRun{{Text: "On vacation!"}},
+		},
+		{
+			name:     "Emoji before text",
+			text:     "🏖 On vacation!",
+			expected: []textRun{{Text: "🏖", Emoji: true}, {Text: " On vacation!"}},
+		},
+		{
+			name:     "Skin tone modifier stays in the cluster",
+			text:     "Hi 👋🏽",
+			expected: []textRun{{Text: "Hi "}, {Text: "👋🏽", Emoji: true}},
+		},
+		{
+			name:     "ZWJ sequence is a single cluster",
+			text:     "👩🏽‍💻 working",
+			expected: []textRun{{Text: "👩🏽‍💻", Emoji: true}, {Text: " working"}},
+		},
+		{
+			name:     "Flags pair regional indicators",
+			text:     "🇬🇪🇯🇵",
+			expected: []textRun{{Text: "🇬🇪", Emoji: true}, {Text: "🇯🇵", Emoji: true}},
+		},
+		{
+			name:     "Keycap sequence",
+			text:     "Back in 3️⃣ days",
+			expected: []textRun{{Text: "Back in "}, {Text: "3️⃣", Emoji: true}, {Text: " days"}},
+		},
+		{
+			name:     "Digits without keycap are text",
+			text:     "Back 25.10",
+			expected: []textRun{{Text: "Back 25.10"}},
+		},
+		{
+			name:     "Text presentation selector keeps the symbol as text",
+			text:     "☀︎ and ☀️",
+			expected: []textRun{{Text: "☀︎ and "}, {Text: "☀️", Emoji: true}},
+		},
+		{
+			name:     "Copyright sign is text without the emoji selector",
+			text:     "© 2024",
+			expected: []textRun{{Text: "© 2024"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := splitEmoji(tt.text)
+			if len(actual) != len(tt.expected) {
+				t.Fatalf("Expected runs %+v, got %+v", tt.expected, actual)
+			}
+			for i := range actual {
+				if actual[i] != tt.expected[i] {
+					t.Errorf("Expected runs %+v, got %+v", tt.expected, actual)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestEmojiFileNames(t *testing.T) {
+	tests := []struct {
+		cluster  string
+		expected []string
+	}{
+		{cluster: "🏖", expected: []string{"1f3d6.png"}},
+		{cluster: "☀️", expected: []string{"2600-fe0f.png", "2600.png"}},
+		{cluster: "👩🏽‍💻", expected: []string{"1f469-1f3fd-200d-1f4bb.png"}},
+	}
+
+	for _, tt := range tests {
+		actual := emojiFileNames(tt.cluster)
+		if len(actual) != len(tt.expected) || actual[0] != tt.expected[0] || actual[len(actual)-1] != tt.expected[len(tt.expected)-1] {
+			t.Errorf("For %q expected %q, got %q", tt.cluster, tt.expected, actual)
+		}
+	}
+}
+
+func TestDrawTextToImageWithEmoji(t *testing.T) {
+	originalAssets := assets
+	assets = overlayFS{os.DirFS("./test_assets"), bundledAssets()}
+	emojiCache = map[string]*image.NRGBA{}
+	defer func() {
+		assets = originalAssets
+		emojiCache = map[string]*image.NRGBA{}
+	}()
+
+	img := image.NewNRGBA(image.Rect(0, 0, 400, 200))
+	layout, err := DrawTextToImage(img, "🏖 Beach", defaultFontFamily, defaultTextStyle, defaultTextLayoutOptions)
+	if err != nil {
+		t.Fatalf("DrawTextToImage failed: %v", err)
+	}
+	if layout.Truncated {
+		t.Errorf("Expected text not to be truncated")
+	}
+
+	emojiColor := color.NRGBA{255, 160, 0, 255}
+	emojiPixels, textPixels := 0, 0
+	for y := 0; y < img.Bounds().Dy(); y++ {
+		for x := 0; x < img.Bounds().Dx(); x++ {
+			switch img.NRGBAAt(x, y) {
+			case emojiColor:
+				emojiPixels++
+			case textColor:
+				textPixels++
+			}
+		}
+	}
+
+	if emojiPixels == 0 {
+		t.Error("Expected the emoji bitmap to be drawn")
+	}
+	if textPixels == 0 {
+		t.Error("Expected the text to be drawn next to the emoji")
+	}
+}
+
+func TestCachedLoadEmojiBundled(t *testing.T) {
+	emojiCache = map[string]*image.NRGBA{}
+	defer func() { emojiCache = map[string]*image.NRGBA{} }()
+
+	for _, cluster := range []string{"😀", "🏖", "🏖️", "👩🏽‍💻", "🇬🇪"} {
+		t.Run(cluster, func(t *testing.T) {
+			bitmap := CachedLoadEmoji(cluster)
+			if bitmap == nil {
+				t.Fatalf("Expected a bundled bitmap for %q", cluster)
+			}
+			if bitmap.Bounds().Empty() {
+				t.Errorf("Expected the bitmap for %q not to be empty", cluster)
+			}
+			if emojiCache[cluster] != bitmap {
+				t.Errorf("Expected the bitmap for %q to be cached", cluster)
+			}
+		})
+	}
+}
+
+func TestDrawTextToImageWithMissingEmoji(t *testing.T) {
+	emojiCache = map[string]*image.NRGBA{}
+	defer func() { emojiCache = map[string]*image.NRGBA{} }()
+
+	// There is no Twemoji bitmap for the playing card, it is drawn as text.
+	cluster := "\U0001f0a1"
+	if CachedLoadEmoji(cluster) != nil {
+		t.Fatalf("Expected no bitmap for %q", cluster)
+	}
+	if _, cached := emojiCache[cluster]; cached {
+		t.Errorf("Expected the missing bitmap for %q not to be cached", cluster)
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, 200, 200))
+	if _, err := DrawTextToImage(img, cluster, defaultFontFamily, defaultTextStyle, defaultTextLayoutOptions); err != nil {
+		t.Fatalf("DrawTextToImage failed: %v", err)
+	}
+
+	textPixels := 0
+	for y := 0; y < img.Bounds().Dy(); y++ {
+		for x := 0; x < img.Bounds().Dx(); x++ {
+			if img.NRGBAAt(x, y) == textColor {
+				textPixels++
+			}
+		}
+	}
+	if textPixels == 0 {
+		t.Error("Expected the emoji to be drawn with the font")
+	}
+}
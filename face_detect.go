@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"io/fs"
+	"math"
+	"sync"
+
+	pigo "github.com/esimov/pigo/core"
+)
+
+// faceCascadePath is the pico face classifier shipped with pigo.
+const faceCascadePath = "cascades/facefinder"
+
+// Detection settings. Faces smaller than minFaceSize of the avatar are too
+// small to be covered by text, weaker matches than minFaceScore are noise.
+const (
+	minFaceSize       = 0.1
+	faceShiftFactor   = 0.1
+	faceScaleFactor   = 1.1
+	faceIoUThreshold  = 0.2
+	minFaceScore      = 5.0
+	faceMarginPercent = 15.0
+)
+
+var (
+	faceClassifier     *pigo.Pigo
+	faceClassifierErr  error
+	faceClassifierOnce sync.Once
+)
+
+func loadFaceClassifier() (*pigo.Pigo, error) {
+	faceClassifierOnce.Do(func() {
+		cascade, err := fs.ReadFile(assets, faceCascadePath)
+		if err != nil {
+			faceClassifierErr = fmt.Errorf("error reading face cascade: %v", err)
+			return
+		}
+
+		faceClassifier, err = pigo.NewPigo().Unpack(cascade)
+		if err != nil {
+			faceClassifierErr = fmt.Errorf("error parsing face cascade: %v", err)
+		}
+	})
+	return faceClassifier, faceClassifierErr
+}
+
+// DetectFaces returns the faces found in img, each grown by
+// faceMarginPercent so hair and chin are avoided along with the eyes.
+func DetectFaces(img *image.NRGBA) ([]image.Rectangle, error) {
+	classifier, err := loadFaceClassifier()
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := img.Bounds()
+	size := min(bounds.Dx(), bounds.Dy())
+	params := pigo.CascadeParams{
+		MinSize:     max(20, int(float64(size)*minFaceSize)),
+		MaxSize:     size,
+		ShiftFactor: faceShiftFactor,
+		ScaleFactor: faceScaleFactor,
+		ImageParams: pigo.ImageParams{
+			Pixels: pigo.RgbToGrayscale(img),
+			Rows:   bounds.Dy(),
+			Cols:   bounds.Dx(),
+			Dim:    bounds.Dx(),
+		},
+	}
+
+	detections := classifier.ClusterDetections(classifier.RunCascade(params, 0), faceIoUThreshold)
+
+	var faces []image.Rectangle
+	for _, detection := range detections {
+		if detection.Q < minFaceScore {
+			continue
+		}
+
+		half := float64(detection.Scale) / 2 * (1 + faceMarginPercent/100)
+		face := image.Rect(
+			bounds.Min.X+int(float64(detection.Col)-half), bounds.Min.Y+int(float64(detection.Row)-half),
+			bounds.Min.X+int(math.Ceil(float64(detection.Col)+half)), bounds.Min.Y+int(math.Ceil(float64(detection.Row)+half)),
+		)
+		faces = append(faces, face.Intersect(bounds))
+	}
+	return faces, nil
+}
+
+// Text moved off a face needs room: a band narrower than minTextBandWidth or
+// lower than minTextBandHeight of the avatar is not used.
+const (
+	minTextBandWidth  = 0.4
+	minTextBandHeight = 0.2
+)
+
+// faceFreeRegion returns the largest band of bounds next to the faces: below,
+// above, left or right of all of them. ok is false when there are no faces
+// or no band is large enough, text then stays centered. With circle set the
+// band is cut down to a rectangle inside the inscribed circle.
+func faceFreeRegion(bounds image.Rectangle, faces []image.Rectangle, circle bool) (image.Rectangle, bool) {
+	if len(faces) == 0 {
+		return image.Rectangle{}, false
+	}
+
+	covered := faces[0]
+	for _, face := range faces[1:] {
+		covered = covered.Union(face)
+	}
+
+	// Bottom comes first so it wins ties, text under the face is the most
+	// natural spot on a portrait.
+	bands := []struct {
+		rect       image.Rectangle
+		horizontal bool
+	}{
+		{image.Rect(bounds.Min.X, covered.Max.Y, bounds.Max.X, bounds.Max.Y), true},
+		{image.Rect(bounds.Min.X, bounds.Min.Y, bounds.Max.X, covered.Min.Y), true},
+		{image.Rect(bounds.Min.X, bounds.Min.Y, covered.Min.X, bounds.Max.Y), false},
+		{image.Rect(covered.Max.X, bounds.Min.Y, bounds.Max.X, bounds.Max.Y), false},
+	}
+
+	var best image.Rectangle
+	for _, candidate := range bands {
+		band := candidate.rect
+		if circle && !band.Empty() {
+			band = bandInCircle(bounds, band, candidate.horizontal)
+		}
+		if float64(band.Dx()) < minTextBandWidth*float64(bounds.Dx()) || float64(band.Dy()) < minTextBandHeight*float64(bounds.Dy()) {
+			continue
+		}
+		if band.Dx()*band.Dy() > best.Dx()*best.Dy() {
+			best = band
+		}
+	}
+	return best, !best.Empty()
+}
+
+// bandInCircle finds the largest rectangle within band that is inside the
+// circle inscribed in bounds, with circleSafeMargin kept from its edge.
+// Horizontal bands span the width of bounds, the others its height.
+func bandInCircle(bounds, band image.Rectangle, horizontal bool) image.Rectangle {
+	centerX, centerY, radius := inscribedCircle(bounds)
+	radius *= 1 - circleSafeMargin
+
+	// Along is the direction the band is cut in, across the one the chord of
+	// the circle limits.
+	alongMin, alongMax, alongCenter := band.Min.Y, band.Max.Y, centerY
+	acrossMin, acrossMax, acrossCenter := float64(band.Min.X), float64(band.Max.X), centerX
+	if !horizontal {
+		alongMin, alongMax, alongCenter = band.Min.X, band.Max.X, centerX
+		acrossMin, acrossMax, acrossCenter = float64(band.Min.Y), float64(band.Max.Y), centerY
+	}
+
+	step := max(1, (alongMax-alongMin)/128)
+	bestArea := 0.0
+	var best image.Rectangle
+	for low := alongMin; low < alongMax; low += step {
+		for high := low + step; high <= alongMax; high += step {
+			// The chord is shortest at the edge farthest from the center.
+			distance := math.Max(math.Abs(float64(low)-alongCenter), math.Abs(float64(high)-alongCenter))
+			halfWidth := math.Min(chordWidth(radius, distance)/2, math.Min(acrossCenter-acrossMin, acrossMax-acrossCenter))
+			if area := float64(high-low) * halfWidth; area > bestArea {
+				bestArea = area
+				acrossLow, acrossHigh := int(math.Ceil(acrossCenter-halfWidth)), int(acrossCenter+halfWidth)
+				if horizontal {
+					best = image.Rect(acrossLow, low, acrossHigh, high)
+				} else {
+					best = image.Rect(low, acrossLow, high, acrossHigh)
+				}
+			}
+		}
+	}
+	return best
+}
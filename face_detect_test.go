@@ -0,0 +1,134 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"testing"
+)
+
+// portrait puts the test face at the top of a light avatar, leaving room for
+// text below it.
+func portrait(t *testing.T) *image.NRGBA {
+	face, err := LoadImage("./test_assets/face.png")
+	if err != nil {
+		t.Fatalf("LoadImage failed: %v", err)
+	}
+
+	img := solidImage(256, color.NRGBA{R: 235, G: 235, B: 235, A: 255})
+	draw.Draw(img, face.Bounds().Add(image.Pt(64, 0)), face, image.Point{}, draw.Src)
+	return img
+}
+
+func TestDetectFaces(t *testing.T) {
+	tests := []struct {
+		name     string
+		img      *image.NRGBA
+		expected int
+	}{
+		{name: "Portrait", img: portrait(t), expected: 1},
+		{name: "Plain background", img: solidImage(256, color.NRGBA{R: 90, G: 120, B: 200, A: 255}), expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			faces, err := DetectFaces(tt.img)
+			if err != nil {
+				t.Fatalf("DetectFaces failed: %v", err)
+			}
+			if len(faces) != tt.expected {
+				t.Fatalf("Expected %d faces, got %v", tt.expected, faces)
+			}
+
+			for _, face := range faces {
+				center := image.Pt((face.Min.X+face.Max.X)/2, (face.Min.Y+face.Max.Y)/2)
+				if !center.In(image.Rect(64, 0, 192, 160)) {
+					t.Errorf("Expected the face inside the photo, got %v", face)
+				}
+			}
+		})
+	}
+}
+
+func TestFaceFreeRegion(t *testing.T) {
+	bounds := image.Rect(0, 0, 200, 200)
+
+	tests := []struct {
+		name     string
+		faces    []image.Rectangle
+		circle   bool
+		expected image.Rectangle
+		ok       bool
+	}{
+		{name: "No faces", faces: nil, ok: false},
+		{name: "Face at the top", faces: []image.Rectangle{image.Rect(50, 0, 150, 100)}, expected: image.Rect(0, 100, 200, 200), ok: true},
+		{name: "Face at the bottom", faces: []image.Rectangle{image.Rect(50, 120, 150, 200)}, expected: image.Rect(0, 0, 200, 120), ok: true},
+		{name: "Face on the left", faces: []image.Rectangle{image.Rect(0, 20, 90, 180)}, expected: image.Rect(90, 0, 200, 200), ok: true},
+		{name: "Two faces are avoided together", faces: []image.Rectangle{image.Rect(10, 10, 60, 60), image.Rect(120, 30, 190, 100)}, expected: image.Rect(0, 100, 200, 200), ok: true},
+		{name: "Face filling the avatar", faces: []image.Rectangle{image.Rect(20, 20, 180, 180)}, ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			region, ok := faceFreeRegion(bounds, tt.faces, tt.circle)
+			if ok != tt.ok || region != tt.expected {
+				t.Errorf("Expected %v (%v), got %v (%v)", tt.expected, tt.ok, region, ok)
+			}
+		})
+	}
+}
+
+func TestFaceFreeRegionInsideCircle(t *testing.T) {
+	bounds := image.Rect(0, 0, 200, 200)
+	region, ok := faceFreeRegion(bounds, []image.Rectangle{image.Rect(50, 0, 150, 100)}, true)
+	if !ok {
+		t.Fatal("Expected a region below the face")
+	}
+
+	if region.Min.Y < 100 {
+		t.Errorf("Expected the region below the face, got %v", region)
+	}
+	centerX, centerY, radius := inscribedCircle(bounds)
+	for _, corner := range []image.Point{region.Min, {region.Max.X, region.Min.Y}, {region.Min.X, region.Max.Y}, region.Max} {
+		if distance := math.Hypot(float64(corner.X)-centerX, float64(corner.Y)-centerY); distance > radius*(1-circleSafeMargin)+1 {
+			t.Errorf("Expected %v inside the circle, corner %v is %.1f from the center", region, corner, distance)
+		}
+	}
+}
+
+func TestRenderTemplateAvoidsFaces(t *testing.T) {
+	img := portrait(t)
+	faces, err := DetectFaces(img)
+	if err != nil || len(faces) != 1 {
+		t.Fatalf("Expected one face, got %v (%v)", faces, err)
+	}
+
+	original := image.NewNRGBA(img.Bounds())
+	copy(original.Pix, img.Pix)
+
+	tmpl := Template{Name: "text", Layers: []Layer{{Type: LayerText}}}
+	if _, err := RenderTemplate(img, tmpl, "Day off!", defaultUserSettings()); err != nil {
+		t.Fatalf("RenderTemplate failed: %v", err)
+	}
+
+	changed, changedOnFace := 0, 0
+	for y := 0; y < 256; y++ {
+		for x := 0; x < 256; x++ {
+			if img.NRGBAAt(x, y) == original.NRGBAAt(x, y) {
+				continue
+			}
+			changed++
+			if image.Pt(x, y).In(faces[0]) {
+				changedOnFace++
+			}
+		}
+	}
+
+	if changed == 0 {
+		t.Fatal("Expected text to be drawn")
+	}
+	if changedOnFace > 0 {
+		t.Errorf("Expected the text to stay off the face %v, %d pixels changed on it", faces[0], changedOnFace)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"image"
+	"image/draw"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// fallbackFace draws every rune with the first font of the chain that has a
+// glyph for it, so mixed-script text can be drawn and measured by a regular
+// font.Drawer. Metrics come from the primary font to keep line spacing stable.
+// collection and size let text that needs shaping bypass the rune by rune path.
+// Faces of a FontCache are shared by renders running at the same time, and
+// neither buf nor the faces underneath are safe for concurrent use, so mu
+// guards every call.
+type fallbackFace struct {
+	collection *FontCache
+	size       float64
+
+	mu    sync.Mutex
+	fonts []*sfnt.Font
+	faces []font.Face
+	buf   sfnt.Buffer
+}
+
+// faceFor must be called with mu held.
+func (f *fallbackFace) faceFor(r rune) font.Face {
+	for i, ttf := range f.fonts {
+		if index, err := ttf.GlyphIndex(&f.buf, r); err == nil && index != 0 {
+			return f.faces[i]
+		}
+	}
+	return f.faces[0]
+}
+
+func (f *fallbackFace) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var firstErr error
+	for _, face := range f.faces {
+		if err := face.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Glyph returns a copy of the mask, the face underneath reuses its own for
+// the next glyph.
+func (f *fallbackFace) Glyph(dot fixed.Point26_6, r rune) (image.Rectangle, image.Image, image.Point, fixed.Int26_6, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	dr, mask, maskp, advance, ok := f.faceFor(r).Glyph(dot, r)
+	if !ok || mask == nil {
+		return dr, mask, maskp, advance, ok
+	}
+
+	glyph := image.NewAlpha(image.Rect(0, 0, dr.Dx(), dr.Dy()))
+	draw.Draw(glyph, glyph.Bounds(), mask, maskp, draw.Src)
+	return dr, glyph, image.Point{}, advance, ok
+}
+
+func (f *fallbackFace) GlyphBounds(r rune) (fixed.Rectangle26_6, fixed.Int26_6, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.faceFor(r).GlyphBounds(r)
+}
+
+func (f *fallbackFace) GlyphAdvance(r rune) (fixed.Int26_6, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.faceFor(r).GlyphAdvance(r)
+}
+
+// Kern only applies between runes drawn with the same font.
+func (f *fallbackFace) Kern(r0, r1 rune) fixed.Int26_6 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	face := f.faceFor(r0)
+	if face != f.faceFor(r1) {
+		return 0
+	}
+	return face.Kern(r0, r1)
+}
+
+func (f *fallbackFace) Metrics() font.Metrics {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.faces[0].Metrics()
+}
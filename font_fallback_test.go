@@ -0,0 +1,148 @@
+package main
+
+import (
+	"image"
+	"sync"
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestFallbackFaceChoosesFontWithGlyph(t *testing.T) {
+	ttfFont, err := CachedLoadDefaultFont()
+	if err != nil {
+		t.Fatalf("Failed to load fonts: %v", err)
+	}
+
+	face, ok := ttfFont.defaultFace.(*fallbackFace)
+	if !ok {
+		t.Fatalf("Expected a fallback face, got %T", ttfFont.defaultFace)
+	}
+
+	tests := []struct {
+		name      string
+		r         rune
+		fontIndex int
+	}{
+		{name: "Latin uses the primary font", r: 'A', fontIndex: 0},
+		{name: "Cyrillic uses the primary font", r: 'Ж', fontIndex: 0},
+		{name: "Georgian falls back", r: 'ა', fontIndex: 1},
+		{name: "Hebrew falls back", r: 'ש', fontIndex: 1},
+		{name: "Missing everywhere uses the primary font", r: '\U0010fffd', fontIndex: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := face.faceFor(tt.r); actual != face.faces[tt.fontIndex] {
+				t.Errorf("Expected rune %q to use font %d", tt.r, tt.fontIndex)
+			}
+		})
+	}
+}
+
+func TestFallbackFaceHasCJKGlyphs(t *testing.T) {
+	ttfFont, err := CachedLoadDefaultFont()
+	if err != nil {
+		t.Fatalf("Failed to load fonts: %v", err)
+	}
+
+	face, ok := ttfFont.defaultFace.(*fallbackFace)
+	if !ok {
+		t.Fatalf("Expected a fallback face, got %T", ttfFont.defaultFace)
+	}
+
+	var buf sfnt.Buffer
+	for _, r := range "们这한休あ" {
+		t.Run(string(r), func(t *testing.T) {
+			found := false
+			for _, ttf := range face.fonts {
+				if index, err := ttf.GlyphIndex(&buf, r); err == nil && index != 0 {
+					found = true
+					break
+				}
+			}
+			if !found {
+				t.Errorf("Expected a font in the chain to have a glyph for %q", r)
+			}
+		})
+	}
+}
+
+func TestFallbackFaceMeasuresMixedText(t *testing.T) {
+	ttfFont, err := CachedLoadDefaultFont()
+	if err != nil {
+		t.Fatalf("Failed to load fonts: %v", err)
+	}
+
+	var buf sfnt.Buffer
+	if index, _ := ttfFont.fonts[0].GlyphIndex(&buf, 'ა'); index != 0 {
+		t.Skip("Primary font has Georgian glyphs, nothing to fall back to")
+	}
+
+	drawer := &font.Drawer{Face: ttfFont.defaultFace}
+	latin := measureLineWidth(drawer, "Hi ")
+	georgian := measureLineWidth(drawer, "გამარჯობა")
+	mixed := measureLineWidth(drawer, "Hi გამარჯობა")
+
+	if georgian <= 0 {
+		t.Fatalf("Expected Georgian text to have a width, got %.1f", georgian)
+	}
+	if mixed != latin+georgian {
+		t.Errorf("Expected mixed width %.1f, got %.1f", latin+georgian, mixed)
+	}
+}
+
+func TestDrawTextToImageWithFallbackScripts(t *testing.T) {
+	for _, text := range []string{"გამარჯობა", "שלום", "Привет"} {
+		t.Run(text, func(t *testing.T) {
+			img := image.NewNRGBA(image.Rect(0, 0, 300, 150))
+			_, err := DrawTextToImage(img, text, defaultFontFamily, defaultTextStyle, defaultTextLayoutOptions)
+			if err != nil {
+				t.Fatalf("DrawTextToImage failed: %v", err)
+			}
+
+			drawn := 0
+			for i := 3; i < len(img.Pix); i += 4 {
+				if img.Pix[i] > 0 {
+					drawn++
+				}
+			}
+			if drawn == 0 {
+				t.Error("Expected glyphs to be drawn")
+			}
+		})
+	}
+}
+
+// The cached default face is shared by renders running at the same time, run
+// with -race to check it.
+func TestFallbackFaceConcurrentUse(t *testing.T) {
+	ttfFont, err := CachedLoadDefaultFont()
+	if err != nil {
+		t.Fatalf("Failed to load fonts: %v", err)
+	}
+
+	texts := []string{"On vacation", "В отпуске", "შვებულებაში", "休暇中", "🏖 Back soon"}
+	widths := make([]float64, len(texts))
+	for i, text := range texts {
+		widths[i] = measureLineWidth(&font.Drawer{Face: ttfFont.defaultFace}, text)
+	}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i, text := range texts {
+				if width := measureLineWidth(&font.Drawer{Face: ttfFont.defaultFace}, text); width != widths[i] {
+					t.Errorf("Expected %q to measure %v, got %v", text, widths[i], width)
+				}
+				LayoutText(ttfFont.defaultFace, text, image.Rect(0, 0, 300, 300), defaultTextLayoutOptions)
+				newTextCanvas(image.Rect(0, 0, 300, 60)).drawLine(ttfFont.defaultFace, fixed.P(10, 40), text)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// fontFamilies maps the names users pick from to the primary font asset. The
+// fallback chain is appended to every family, so any of them can draw any
+// script the bundled fonts cover. Fonts an operator drops into fontFamilyDir
+// of the override directory are added at startup.
+var fontFamilies = map[string]string{
+	"roboto":      "Roboto-Regular.ttf",
+	"bold":        "fonts/DejaVuSans-Bold.ttf",
+	"serif":       "fonts/DejaVuSerif.ttf",
+	"serif-bold":  "fonts/DejaVuSerif-Bold.ttf",
+	"italic":      "fonts/Go-Italic.ttf",
+	"bold-italic": "fonts/Go-Bold-Italic.ttf",
+	"smallcaps":   "fonts/Go-Smallcaps.ttf",
+	"mono":        "fonts/DejaVuSansMono.ttf",
+	"display":     "fonts/PressStart2P-Regular.ttf",
+}
+
+var fontFamilyNames = []string{"roboto", "bold", "serif", "serif-bold", "italic", "bold-italic", "smallcaps", "mono", "display"}
+
+const defaultFontFamily = "roboto"
+
+const fontFamilyDir = "fonts"
+
+func fontFamilyPaths(name string) ([]string, error) {
+	path, ok := fontFamilies[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown font family %q", name)
+	}
+	return fontPathsFor(path), nil
+}
+
+func CachedLoadFontFamily(name string) (*FontCache, error) {
+	paths, err := fontFamilyPaths(name)
+	if err != nil {
+		return nil, err
+	}
+	return CachedLoadFont(paths...)
+}
+
+const (
+	fontPreviewWidth     = 480
+	fontPreviewRowHeight = 56
+	fontPreviewFontSize  = 32
+)
+
+var fontPreviewBackground = color.NRGBA{R: 40, G: 40, B: 56, A: 255}
+
+// RenderFontPreview draws every family's button label in the family itself,
+// one row per family in menu order, so users can see the fonts before
+// picking one.
+func RenderFontPreview() (*image.NRGBA, error) {
+	img := image.NewNRGBA(image.Rect(0, 0, fontPreviewWidth, fontPreviewRowHeight*len(fontFamilyNames)))
+	draw.Draw(img, img.Bounds(), image.NewUniform(fontPreviewBackground), image.Point{}, draw.Src)
+
+	canvas := newTextCanvas(img.Bounds())
+	for i, name := range fontFamilyNames {
+		ttfFont, err := CachedLoadFontFamily(name)
+		if err != nil {
+			return nil, err
+		}
+
+		face, err := ttfFont.NewFace(fontPreviewFontSize)
+		if err != nil {
+			return nil, fmt.Errorf("error creating preview font face: %v", err)
+		}
+
+		metrics := face.Metrics()
+		baseline := fixed.I(i*fontPreviewRowHeight) + (fixed.I(fontPreviewRowHeight)+metrics.Ascent-metrics.Descent)/2
+		canvas.drawLine(face, fixed.Point26_6{X: fixed.I(fontPreviewFontSize / 2), Y: baseline}, choiceLabel(name))
+		face.Close()
+	}
+	paintText(img, canvas, defaultTextStyle, fontPreviewFontSize)
+
+	return img, nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"image"
+	"testing"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+)
+
+func TestFontFamiliesLoad(t *testing.T) {
+	if len(fontFamilyNames) != len(fontFamilies) {
+		t.Fatalf("Expected %d family names, got %d", len(fontFamilies), len(fontFamilyNames))
+	}
+
+	widths := map[float64]string{}
+	for _, name := range fontFamilyNames {
+		t.Run(name, func(t *testing.T) {
+			ttfFont, err := CachedLoadFontFamily(name)
+			if err != nil {
+				t.Fatalf("Failed to load font family: %v", err)
+			}
+
+			width := measureLineWidth(&font.Drawer{Face: ttfFont.defaultFace}, "Out of office")
+			if other, ok := widths[width]; ok {
+				t.Errorf("Expected %s to differ from %s", name, other)
+			}
+			widths[width] = name
+		})
+	}
+}
+
+func TestFontFamiliesDrawLatinThemselves(t *testing.T) {
+	var buf sfnt.Buffer
+	for _, name := range fontFamilyNames {
+		t.Run(name, func(t *testing.T) {
+			ttfFont, err := CachedLoadFontFamily(name)
+			if err != nil {
+				t.Fatalf("Failed to load font family: %v", err)
+			}
+
+			face, ok := ttfFont.defaultFace.(*fallbackFace)
+			if !ok {
+				t.Fatalf("Expected a fallback face, got %T", ttfFont.defaultFace)
+			}
+
+			for _, r := range "Out of office!" {
+				if index, err := face.fonts[0].GlyphIndex(&buf, r); err != nil || index == 0 {
+					t.Errorf("Expected the %s font to have a glyph for %q", name, r)
+				}
+			}
+		})
+	}
+}
+
+func TestCachedLoadFontFamilyUnknown(t *testing.T) {
+	if _, err := CachedLoadFontFamily("comic"); err == nil {
+		t.Error("Expected an error for an unknown font family")
+	}
+}
+
+func TestDrawTextToImageUsesFontFamily(t *testing.T) {
+	render := func(fontFamily string) *image.NRGBA {
+		img := image.NewNRGBA(image.Rect(0, 0, 300, 150))
+		_, err := DrawTextToImage(img, "Day off!", fontFamily, defaultTextStyle, defaultTextLayoutOptions)
+		if err != nil {
+			t.Fatalf("DrawTextToImage failed: %v", err)
+		}
+		return img
+	}
+
+	if CompareImages(render(defaultFontFamily), render("serif")) {
+		t.Error("Expected the serif family to render differently from the default")
+	}
+}
+
+func TestRenderFontPreview(t *testing.T) {
+	preview, err := RenderFontPreview()
+	if err != nil {
+		t.Fatalf("RenderFontPreview failed: %v", err)
+	}
+
+	if expected := fontPreviewRowHeight * len(fontFamilyNames); preview.Bounds().Dy() != expected {
+		t.Fatalf("Expected height %d, got %d", expected, preview.Bounds().Dy())
+	}
+
+	for i, name := range fontFamilyNames {
+		drawn := false
+		for y := i * fontPreviewRowHeight; y < (i+1)*fontPreviewRowHeight && !drawn; y++ {
+			for x := 0; x < preview.Bounds().Dx(); x++ {
+				if preview.NRGBAAt(x, y) == textColor {
+					drawn = true
+					break
+				}
+			}
+		}
+		if !drawn {
+			t.Errorf("Expected the %s row to have text", name)
+		}
+	}
+}
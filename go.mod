@@ -3,12 +3,12 @@ module github.com/vivcogit/vacato-bot
 go 1.23.1
 
 require (
+	github.com/esimov/pigo v1.4.6
 	github.com/go-telegram-bot-api/telegram-bot-api/v5 v5.5.1
+	github.com/go-text/typesetting v0.2.1
 	github.com/sirupsen/logrus v1.9.3
 	golang.org/x/image v0.21.0
+	golang.org/x/text v0.19.0
 )
 
-require (
-	golang.org/x/sys v0.26.0 // indirect
-	golang.org/x/text v0.19.0 // indirect
-)
+require golang.org/x/sys v0.26.0 // indirect
@@ -1,48 +1,256 @@
 package main
 
 import (
+	"encoding/binary"
 	"hash/fnv"
 	"image"
 	"image/color"
+	"math"
+	"sort"
+	"sync"
 )
 
-func CreateGradient(width, height int, startColor, endColor color.NRGBA) *image.NRGBA {
+type GradientKind uint8
+
+const (
+	GradientLinear GradientKind = iota
+	GradientRadial
+	GradientConic
+)
+
+// GradientShape describes how colors flow across the image.
+// Angle is in degrees: for linear gradients 0 flows top to bottom and 90 flows
+// left to right, for conic gradients it is the start direction of the sweep.
+// CenterX and CenterY are relative to the image size (0.5, 0.5 is the middle)
+// and are used by radial and conic gradients.
+type GradientShape struct {
+	Kind    GradientKind
+	Angle   float64
+	CenterX float64
+	CenterY float64
+}
+
+func LinearGradient(angle float64) GradientShape {
+	return GradientShape{Kind: GradientLinear, Angle: angle}
+}
+
+func RadialGradient(centerX, centerY float64) GradientShape {
+	return GradientShape{Kind: GradientRadial, CenterX: centerX, CenterY: centerY}
+}
+
+func ConicGradient(centerX, centerY, angle float64) GradientShape {
+	return GradientShape{Kind: GradientConic, Angle: angle, CenterX: centerX, CenterY: centerY}
+}
+
+var gradientShapes = map[string]GradientShape{
+	"vertical": LinearGradient(0),
+	"diagonal": LinearGradient(45),
+	"radial":   RadialGradient(0.5, 0.5),
+	"sunset":   RadialGradient(0.5, 1),
+	"conic":    ConicGradient(0.5, 0.5, 0),
+}
+
+var gradientShapeNames = []string{"vertical", "diagonal", "radial", "sunset", "conic"}
+
+var defaultGradientShape = gradientShapes["vertical"]
+
+// GradientStop is a color pinned at Position (0 is the start of the gradient,
+// 1 is the end). The alpha of Color is the opacity of the stop.
+type GradientStop struct {
+	Position float64
+	Color    color.NRGBA
+}
+
+type GradientSpec struct {
+	Shape GradientShape
+	Stops []GradientStop
+	Space ColorSpace
+}
+
+func TwoColorGradient(shape GradientShape, startColor, endColor color.NRGBA) GradientSpec {
+	return GradientSpec{
+		Shape: shape,
+		Stops: []GradientStop{
+			{Position: 0, Color: startColor},
+			{Position: 1, Color: endColor},
+		},
+	}
+}
+
+var gradientPalettes = map[string][]GradientStop{
+	"classic": {
+		{Position: 0, Color: color.NRGBA{R: 0, G: 0, B: 255, A: 255}},
+		{Position: 1, Color: color.NRGBA{R: 255, G: 0, B: 255, A: 255}},
+	},
+	"beach": {
+		{Position: 0, Color: color.NRGBA{R: 0, G: 128, B: 128, A: 255}},
+		{Position: 0.5, Color: color.NRGBA{R: 225, G: 200, B: 150, A: 255}},
+		{Position: 1, Color: color.NRGBA{R: 255, G: 127, B: 80, A: 255}},
+	},
+	"sunrise": {
+		{Position: 0, Color: color.NRGBA{R: 255, G: 94, B: 77, A: 255}},
+		{Position: 0.4, Color: color.NRGBA{R: 255, G: 154, B: 0, A: 255}},
+		{Position: 0.7, Color: color.NRGBA{R: 255, G: 206, B: 84, A: 255}},
+		{Position: 1, Color: color.NRGBA{R: 255, G: 238, B: 173, A: 160}},
+	},
+	"forest": {
+		{Position: 0, Color: color.NRGBA{R: 19, G: 78, B: 94, A: 255}},
+		{Position: 0.6, Color: color.NRGBA{R: 113, G: 178, B: 128, A: 255}},
+		{Position: 1, Color: color.NRGBA{R: 240, G: 230, B: 140, A: 128}},
+	},
+}
+
+var gradientPaletteNames = []string{"classic", "beach", "sunrise", "forest", autoGradientPalette}
+
+const defaultGradientPalette = "classic"
+
+func CreateGradient(width, height int, spec GradientSpec) *image.NRGBA {
 	gradientImg := image.NewNRGBA(image.Rect(0, 0, width, height))
+	position := gradientPositionFunc(width, height, spec.Shape)
+	stops := sortedStops(spec.Stops)
 
 	for y := 0; y < height; y++ {
-		alpha := float64(y) / float64(height)
-		colorRow := BlendColors(startColor, endColor, alpha)
-
 		for x := 0; x < width; x++ {
-			gradientImg.SetNRGBA(x, y, colorRow)
+			gradientImg.SetNRGBA(x, y, colorAtPosition(stops, position(x, y), spec.Space))
 		}
 	}
 
 	return gradientImg
 }
 
-func getGradientKey(width, height int, startColor, endColor color.NRGBA) uint32 {
+func sortedStops(stops []GradientStop) []GradientStop {
+	sorted := make([]GradientStop, len(stops))
+	copy(sorted, stops)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Position < sorted[j].Position
+	})
+	return sorted
+}
+
+// colorAtPosition expects stops ordered by position. Positions before the
+// first stop or after the last one take the color of that stop.
+func colorAtPosition(stops []GradientStop, position float64, space ColorSpace) color.NRGBA {
+	if len(stops) == 0 {
+		return color.NRGBA{}
+	}
+	if position <= stops[0].Position {
+		return stops[0].Color
+	}
+
+	for i := 1; i < len(stops); i++ {
+		prev, next := stops[i-1], stops[i]
+		if position > next.Position {
+			continue
+		}
+
+		span := next.Position - prev.Position
+		if span == 0 {
+			return next.Color
+		}
+		return InterpolateColors(prev.Color, next.Color, (position-prev.Position)/span, space)
+	}
+
+	return stops[len(stops)-1].Color
+}
+
+// gradientPositionFunc returns a function mapping a pixel to its position
+// along the gradient, in the range [0, 1].
+func gradientPositionFunc(width, height int, shape GradientShape) func(x, y int) float64 {
+	w, h := float64(width), float64(height)
+
+	switch shape.Kind {
+	case GradientRadial:
+		cx, cy := shape.CenterX*w, shape.CenterY*h
+		radius := math.Max(
+			math.Max(math.Hypot(cx, cy), math.Hypot(w-cx, cy)),
+			math.Max(math.Hypot(cx, h-cy), math.Hypot(w-cx, h-cy)),
+		)
+		if radius == 0 {
+			return func(x, y int) float64 { return 0 }
+		}
+
+		return func(x, y int) float64 {
+			return clampUnit(math.Hypot(float64(x)-cx, float64(y)-cy) / radius)
+		}
+
+	case GradientConic:
+		cx, cy := shape.CenterX*w, shape.CenterY*h
+		start := shape.Angle * math.Pi / 180
+
+		return func(x, y int) float64 {
+			angle := math.Atan2(float64(x)-cx, float64(y)-cy) - start
+			angle = math.Mod(angle, 2*math.Pi)
+			if angle < 0 {
+				angle += 2 * math.Pi
+			}
+			return angle / (2 * math.Pi)
+		}
+
+	default:
+		rad := shape.Angle * math.Pi / 180
+		dirX, dirY := math.Sin(rad), math.Cos(rad)
+
+		minProjection := math.Min(0, dirX*w) + math.Min(0, dirY*h)
+		maxProjection := math.Max(0, dirX*w) + math.Max(0, dirY*h)
+		length := maxProjection - minProjection
+		if length == 0 {
+			return func(x, y int) float64 { return 0 }
+		}
+
+		return func(x, y int) float64 {
+			return clampUnit((float64(x)*dirX + float64(y)*dirY - minProjection) / length)
+		}
+	}
+}
+
+func clampUnit(value float64) float64 {
+	if value < 0 {
+		return 0
+	}
+	if value > 1 {
+		return 1
+	}
+	return value
+}
+
+func getGradientKey(width, height int, spec GradientSpec) uint32 {
 	hasher := fnv.New32a()
 
 	buf := []byte{
 		byte(width), byte(width >> 8),
 		byte(height), byte(height >> 8),
-		startColor.R, startColor.G, startColor.B, startColor.A,
-		endColor.R, endColor.G, endColor.B, endColor.A,
+		byte(spec.Shape.Kind),
+		byte(spec.Space),
+	}
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(spec.Shape.Angle))
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(spec.Shape.CenterX))
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(spec.Shape.CenterY))
+
+	for _, stop := range spec.Stops {
+		buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(stop.Position))
+		buf = append(buf, stop.Color.R, stop.Color.G, stop.Color.B, stop.Color.A)
 	}
+
 	hasher.Write(buf)
 	return hasher.Sum32()
 }
 
-var gradientCache = map[uint32]*image.NRGBA{}
+var (
+	gradientCache   = map[uint32]*image.NRGBA{}
+	gradientCacheMu sync.Mutex
+)
+
+func CachedCreateGradient(width, height int, spec GradientSpec) *image.NRGBA {
+	key := getGradientKey(width, height, spec)
+
+	gradientCacheMu.Lock()
+	defer gradientCacheMu.Unlock()
 
-func CachedCreateGradient(width, height int, startColor, endColor color.NRGBA) *image.NRGBA {
-	key := getGradientKey(width, height, startColor, endColor)
 	if cacheValue, cacheExists := gradientCache[key]; cacheExists {
 		return cacheValue
 	}
 
-	value := CreateGradient(width, height, startColor, endColor)
+	value := CreateGradient(width, height, spec)
 	gradientCache[key] = value
 
 	return value
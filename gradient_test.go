@@ -9,6 +9,7 @@ func TestGetGradientKey(t *testing.T) {
 	type GradientParams struct {
 		width      int
 		height     int
+		shape      GradientShape
 		startColor color.NRGBA
 		endColor   color.NRGBA
 	}
@@ -99,12 +100,66 @@ func TestGetGradientKey(t *testing.T) {
 			},
 			expectSame: false,
 		},
+		{
+			name: "Different shapes should return different keys",
+			params1: GradientParams{
+				width:      100,
+				height:     200,
+				shape:      LinearGradient(0),
+				startColor: color.NRGBA{R: 255, G: 0, B: 0, A: 255},
+				endColor:   color.NRGBA{R: 0, G: 0, B: 255, A: 255},
+			},
+			params2: GradientParams{
+				width:      100,
+				height:     200,
+				shape:      RadialGradient(0.5, 0.5),
+				startColor: color.NRGBA{R: 255, G: 0, B: 0, A: 255},
+				endColor:   color.NRGBA{R: 0, G: 0, B: 255, A: 255},
+			},
+			expectSame: false,
+		},
+		{
+			name: "Different radial centers should return different keys",
+			params1: GradientParams{
+				width:      100,
+				height:     200,
+				shape:      RadialGradient(0.5, 0.5),
+				startColor: color.NRGBA{R: 255, G: 0, B: 0, A: 255},
+				endColor:   color.NRGBA{R: 0, G: 0, B: 255, A: 255},
+			},
+			params2: GradientParams{
+				width:      100,
+				height:     200,
+				shape:      RadialGradient(0.5, 1),
+				startColor: color.NRGBA{R: 255, G: 0, B: 0, A: 255},
+				endColor:   color.NRGBA{R: 0, G: 0, B: 255, A: 255},
+			},
+			expectSame: false,
+		},
+		{
+			name: "Different linear angles should return different keys",
+			params1: GradientParams{
+				width:      100,
+				height:     200,
+				shape:      LinearGradient(0),
+				startColor: color.NRGBA{R: 255, G: 0, B: 0, A: 255},
+				endColor:   color.NRGBA{R: 0, G: 0, B: 255, A: 255},
+			},
+			params2: GradientParams{
+				width:      100,
+				height:     200,
+				shape:      LinearGradient(45),
+				startColor: color.NRGBA{R: 255, G: 0, B: 0, A: 255},
+				endColor:   color.NRGBA{R: 0, G: 0, B: 255, A: 255},
+			},
+			expectSame: false,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			key1 := getGradientKey(tt.params1.width, tt.params1.height, tt.params1.startColor, tt.params1.endColor)
-			key2 := getGradientKey(tt.params2.width, tt.params2.height, tt.params2.startColor, tt.params2.endColor)
+			key1 := getGradientKey(tt.params1.width, tt.params1.height, TwoColorGradient(tt.params1.shape, tt.params1.startColor, tt.params1.endColor))
+			key2 := getGradientKey(tt.params2.width, tt.params2.height, TwoColorGradient(tt.params2.shape, tt.params2.startColor, tt.params2.endColor))
 
 			if (key1 == key2) != tt.expectSame {
 				t.Errorf("Test %q failed: expected keys to be equal: %v, got key1=%d, key2=%d", tt.name, tt.expectSame, key1, key2)
@@ -146,7 +201,7 @@ func TestCreateGradient(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gradientImg := CreateGradient(tt.width, tt.height, tt.startColor, tt.endColor)
+			gradientImg := CreateGradient(tt.width, tt.height, TwoColorGradient(LinearGradient(0), tt.startColor, tt.endColor))
 
 			if gradientImg.Bounds().Dx() != tt.width || gradientImg.Bounds().Dy() != tt.height {
 				t.Fatalf("Expected image size %dx%d, got %dx%d",
@@ -170,8 +225,8 @@ func TestCreateGradient(t *testing.T) {
 }
 
 func TestCreateGradientWithReference(t *testing.T) {
-	generatedImg := CreateGradient(400, 600, color.NRGBA{R: 0, G: 0, B: 255, A: 255},
-		color.NRGBA{R: 255, G: 0, B: 255, A: 255})
+	generatedImg := CreateGradient(400, 600, TwoColorGradient(LinearGradient(0), color.NRGBA{R: 0, G: 0, B: 255, A: 255},
+		color.NRGBA{R: 255, G: 0, B: 255, A: 255}))
 
 	referenceImg, err := LoadImage("./test_assets/gradient.png")
 	if err != nil {
@@ -182,3 +237,107 @@ func TestCreateGradientWithReference(t *testing.T) {
 		t.Error("Generated image does not match the reference image")
 	}
 }
+
+func TestCreateGradientShapes(t *testing.T) {
+	black := color.NRGBA{0, 0, 0, 255}
+	white := color.NRGBA{255, 255, 255, 255}
+
+	tests := []struct {
+		name     string
+		shape    GradientShape
+		x, y     int
+		expected color.NRGBA
+	}{
+		{
+			name:     "Horizontal gradient starts at the left edge",
+			shape:    LinearGradient(90),
+			x:        0,
+			y:        50,
+			expected: black,
+		},
+		{
+			name:     "Horizontal gradient is uniform along columns",
+			shape:    LinearGradient(90),
+			x:        50,
+			y:        0,
+			expected: BlendColors(black, white, 0.5),
+		},
+		{
+			name:     "Diagonal gradient starts at the top left corner",
+			shape:    LinearGradient(45),
+			x:        0,
+			y:        0,
+			expected: black,
+		},
+		{
+			name:     "Radial gradient starts at the center",
+			shape:    RadialGradient(0.5, 0.5),
+			x:        50,
+			y:        50,
+			expected: black,
+		},
+		{
+			name:     "Conic gradient starts below the center",
+			shape:    ConicGradient(0.5, 0.5, 0),
+			x:        50,
+			y:        90,
+			expected: black,
+		},
+		{
+			name:     "Conic gradient is halfway above the center",
+			shape:    ConicGradient(0.5, 0.5, 0),
+			x:        50,
+			y:        10,
+			expected: BlendColors(black, white, 0.5),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gradientImg := CreateGradient(100, 100, TwoColorGradient(tt.shape, black, white))
+
+			actualColor := gradientImg.NRGBAAt(tt.x, tt.y)
+			if actualColor != tt.expected {
+				t.Errorf("At position (%d,%d), expected color %v, got %v",
+					tt.x, tt.y, tt.expected, actualColor)
+			}
+		})
+	}
+}
+
+func TestCreateGradientMultiStop(t *testing.T) {
+	red := color.NRGBA{255, 0, 0, 255}
+	green := color.NRGBA{0, 255, 0, 255}
+	blue := color.NRGBA{0, 0, 255, 0}
+
+	spec := GradientSpec{
+		Shape: LinearGradient(90),
+		Stops: []GradientStop{
+			{Position: 1, Color: blue},
+			{Position: 0.25, Color: red},
+			{Position: 0.5, Color: green},
+		},
+	}
+	gradientImg := CreateGradient(100, 10, spec)
+
+	tests := []struct {
+		name     string
+		x        int
+		expected color.NRGBA
+	}{
+		{name: "Before the first stop", x: 10, expected: red},
+		{name: "At the first stop", x: 25, expected: red},
+		{name: "Between the first stops", x: 37, expected: BlendColors(red, green, 0.48)},
+		{name: "At the middle stop", x: 50, expected: green},
+		{name: "Between the last stops keeps stop alpha", x: 75, expected: BlendColors(green, blue, 0.5)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actualColor := gradientImg.NRGBAAt(tt.x, 5)
+			if actualColor != tt.expected {
+				t.Errorf("At x=%d, expected color %v, got %v", tt.x, tt.expected, actualColor)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestOverlayImagePreservesTransparency(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     color.NRGBA
+		overlay  color.NRGBA
+		expected color.NRGBA
+	}{
+		{
+			name:     "Fully transparent pixel stays untouched",
+			base:     color.NRGBA{0, 0, 0, 0},
+			overlay:  color.NRGBA{255, 0, 255, 255},
+			expected: color.NRGBA{0, 0, 0, 0},
+		},
+		{
+			name:     "Semi-transparent edge keeps its alpha and is tinted, not darkened",
+			base:     color.NRGBA{200, 200, 200, 64},
+			overlay:  color.NRGBA{0, 0, 255, 255},
+			expected: color.NRGBA{100, 100, 227, 64},
+		},
+		{
+			name:     "Opaque pixel blends as before",
+			base:     color.NRGBA{200, 100, 0, 255},
+			overlay:  color.NRGBA{0, 0, 255, 255},
+			expected: BlendColors(color.NRGBA{200, 100, 0, 255}, color.NRGBA{0, 0, 255, 255}, 0.5),
+		},
+		{
+			name:     "Overlay alpha scales the tint",
+			base:     color.NRGBA{200, 100, 0, 255},
+			overlay:  color.NRGBA{0, 0, 255, 0},
+			expected: color.NRGBA{200, 100, 0, 255},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+			base.SetNRGBA(0, 0, tt.base)
+			overlay := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+			overlay.SetNRGBA(0, 0, tt.overlay)
+
+			OverlayImage(base, overlay, 0.5, BlendNormal, ColorSpaceSRGB)
+
+			if actual := base.NRGBAAt(0, 0); actual != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestCompositeOver(t *testing.T) {
+	tests := []struct {
+		name     string
+		dst      color.NRGBA
+		src      color.NRGBA
+		coverage uint32
+		expected color.NRGBA
+	}{
+		{
+			name:     "Opaque source replaces destination",
+			dst:      color.NRGBA{10, 20, 30, 255},
+			src:      color.NRGBA{255, 255, 255, 255},
+			coverage: 0xffff,
+			expected: color.NRGBA{255, 255, 255, 255},
+		},
+		{
+			name:     "Zero coverage keeps destination",
+			dst:      color.NRGBA{10, 20, 30, 255},
+			src:      color.NRGBA{255, 255, 255, 255},
+			coverage: 0,
+			expected: color.NRGBA{10, 20, 30, 255},
+		},
+		{
+			name:     "Partial coverage over transparent keeps the source color",
+			dst:      color.NRGBA{0, 0, 0, 0},
+			src:      color.NRGBA{255, 255, 255, 255},
+			coverage: 0x8080,
+			expected: color.NRGBA{255, 255, 255, 128},
+		},
+		{
+			name:     "Half transparent over half transparent",
+			dst:      color.NRGBA{0, 0, 255, 128},
+			src:      color.NRGBA{255, 0, 0, 128},
+			coverage: 0xffff,
+			expected: color.NRGBA{170, 0, 85, 192},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := CompositeOver(tt.dst, tt.src, tt.coverage); actual != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDrawTextToImageOnTransparentImage(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 200, 100))
+	_, err := DrawTextToImage(img, "Hello", defaultFontFamily, defaultTextStyle, defaultTextLayoutOptions)
+	if err != nil {
+		t.Fatalf("DrawTextToImage failed: %v", err)
+	}
+
+	if corner := img.NRGBAAt(0, 0); corner.A != 0 {
+		t.Errorf("Expected transparent corner, got %v", corner)
+	}
+
+	drawn := 0
+	for y := 0; y < img.Bounds().Dy(); y++ {
+		for x := 0; x < img.Bounds().Dx(); x++ {
+			pixel := img.NRGBAAt(x, y)
+			if pixel.A == 0 {
+				continue
+			}
+			drawn++
+			if pixel.R != 255 || pixel.G != 255 || pixel.B != 255 {
+				t.Fatalf("Expected white glyph pixel at (%d,%d), got %v", x, y, pixel)
+			}
+		}
+	}
+	if drawn == 0 {
+		t.Error("Expected some text to be drawn")
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// OutputShape is the outline of the final avatar. Circular output has
+// transparent corners, so it can be used as a profile picture or sticker as
+// is. RingWidth is a fraction of the diameter, zero disables the ring.
+type OutputShape struct {
+	Circle    bool
+	RingColor color.NRGBA
+	RingWidth float64
+}
+
+var outputShapes = map[string]OutputShape{
+	"square": {},
+	"circle": {Circle: true},
+	"ring":   {Circle: true, RingColor: textColor, RingWidth: 0.03},
+}
+
+var outputShapeNames = []string{"square", "circle", "ring"}
+
+const defaultOutputShape = "square"
+
+// inscribedCircle returns the center and radius of the largest circle that
+// fits in bounds.
+func inscribedCircle(bounds image.Rectangle) (float64, float64, float64) {
+	centerX := float64(bounds.Min.X+bounds.Max.X) / 2
+	centerY := float64(bounds.Min.Y+bounds.Max.Y) / 2
+	radius := float64(min(bounds.Dx(), bounds.Dy())) / 2
+	return centerX, centerY, radius
+}
+
+// circleCoverage is the antialiased coverage of the pixel at x, y by a disc,
+// estimated from the distance of the pixel center to the edge.
+func circleCoverage(x, y int, centerX, centerY, radius float64) float64 {
+	distance := math.Hypot(float64(x)+0.5-centerX, float64(y)+0.5-centerY)
+	return clampUnit(radius - distance + 0.5)
+}
+
+// ApplyOutputShape cuts img to the shape in place, drawing the ring first so
+// its outer edge is antialiased by the same circle as the photo.
+func ApplyOutputShape(img *image.NRGBA, shape OutputShape) {
+	if !shape.Circle {
+		return
+	}
+
+	bounds := img.Bounds()
+	centerX, centerY, radius := inscribedCircle(bounds)
+
+	if shape.RingWidth > 0 && shape.RingColor.A > 0 {
+		innerRadius := radius - shape.RingWidth*2*radius
+		ring := image.NewAlpha(bounds)
+
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				coverage := 1 - circleCoverage(x, y, centerX, centerY, innerRadius)
+				ring.SetAlpha(x, y, color.Alpha{A: uint8(math.Round(coverage * 255))})
+			}
+		}
+		FillMask(img, ring, shape.RingColor)
+	}
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			coverage := circleCoverage(x, y, centerX, centerY, radius)
+			if coverage == 1 {
+				continue
+			}
+			if coverage == 0 {
+				img.SetNRGBA(x, y, color.NRGBA{})
+				continue
+			}
+
+			pixel := img.NRGBAAt(x, y)
+			pixel.A = uint8(math.Round(float64(pixel.A) * coverage))
+			img.SetNRGBA(x, y, pixel)
+		}
+	}
+}
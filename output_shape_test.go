@@ -0,0 +1,89 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func solidImage(size int, c color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+	return img
+}
+
+func TestApplyOutputShape(t *testing.T) {
+	photo := color.NRGBA{R: 30, G: 120, B: 200, A: 255}
+
+	tests := []struct {
+		name     string
+		shape    string
+		x, y     int
+		expected color.NRGBA
+	}{
+		{name: "Square keeps corners", shape: "square", x: 0, y: 0, expected: photo},
+		{name: "Circle clears corners", shape: "circle", x: 0, y: 0, expected: color.NRGBA{}},
+		{name: "Circle keeps the center", shape: "circle", x: 50, y: 50, expected: photo},
+		{name: "Circle keeps the inside of the edge", shape: "circle", x: 50, y: 1, expected: photo},
+		{name: "Ring paints the edge", shape: "ring", x: 50, y: 2, expected: textColor},
+		{name: "Ring keeps the center", shape: "ring", x: 50, y: 50, expected: photo},
+		{name: "Ring clears corners", shape: "ring", x: 99, y: 99, expected: color.NRGBA{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := solidImage(100, photo)
+			ApplyOutputShape(img, outputShapes[tt.shape])
+
+			if actual := img.NRGBAAt(tt.x, tt.y); actual != tt.expected {
+				t.Errorf("At (%d,%d) expected %v, got %v", tt.x, tt.y, tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestApplyOutputShapeAntialiasesEdge(t *testing.T) {
+	img := solidImage(100, color.NRGBA{R: 255, A: 255})
+	ApplyOutputShape(img, outputShapes["circle"])
+
+	partial := 0
+	for x := 0; x < 100; x++ {
+		if a := img.NRGBAAt(x, 15).A; a > 0 && a < 255 {
+			partial++
+		}
+	}
+	if partial == 0 {
+		t.Error("Expected semi-transparent pixels along the circle edge")
+	}
+}
+
+func TestDrawSignatureInsideCircle(t *testing.T) {
+	for _, name := range []string{"circle", "ring"} {
+		t.Run(name, func(t *testing.T) {
+			shape := outputShapes[name]
+			img := image.NewNRGBA(image.Rect(0, 0, 320, 320))
+			if err := DrawSignature(img, shape); err != nil {
+				t.Fatalf("DrawSignature failed: %v", err)
+			}
+
+			centerX, centerY, radius := inscribedCircle(img.Bounds())
+			innerRadius := radius * (1 - 2*shape.RingWidth)
+			drawn := 0
+			for y := 0; y < 320; y++ {
+				for x := 0; x < 320; x++ {
+					if img.NRGBAAt(x, y).A == 0 {
+						continue
+					}
+					drawn++
+					if circleCoverage(x, y, centerX, centerY, innerRadius) < 1 {
+						t.Fatalf("Signature pixel (%d,%d) is outside the circle", x, y)
+					}
+				}
+			}
+			if drawn == 0 {
+				t.Error("Expected the signature to be drawn")
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Placeholders the user can put in their text, filled in at render time.
+// {until}, {days_left} and {days_left_text}, the count as a phrase in the
+// user's language, come from the last return date they sent.
+var placeholderNames = []string{"name", "username", "until", "days_left", "days_left_text"}
+
+// placeholderPattern matches the known placeholders, and the same wrapped in
+// a second pair of braces, "{{name}}", which is how one is written as is.
+var placeholderPattern = regexp.MustCompile(`\{\{(` + strings.Join(placeholderNames, "|") + `)\}\}|\{(` + strings.Join(placeholderNames, "|") + `)\}`)
+
+// ExpandPlaceholders replaces every known {name} in text with its value,
+// other braces are left as they are. "{{name}}" comes out as "{name}". A
+// known placeholder without a value is an error saying so.
+func ExpandPlaceholders(text string, values map[string]string) (string, error) {
+	var err error
+	expanded := placeholderPattern.ReplaceAllStringFunc(text, func(match string) string {
+		if strings.HasPrefix(match, "{{") {
+			return match[1 : len(match)-1]
+		}
+
+		name := match[1 : len(match)-1]
+		value, ok := values[name]
+		if !ok && err == nil {
+			err = fmt.Errorf("there's nothing to put in {%s} yet", name)
+		}
+		return value
+	})
+	if err != nil {
+		return "", err
+	}
+	return expanded, nil
+}
+
+// placeholderValues fills the placeholders for a user. {username} falls back
+// to the first name for users without one, the return date placeholders are
+// left out until the user has sent a date.
+func placeholderValues(firstName, userName string, away AwayPeriod, now time.Time) map[string]string {
+	values := map[string]string{
+		"name":     firstName,
+		"username": firstName,
+	}
+	if userName != "" {
+		values["username"] = "@" + userName
+	}
+
+	if !away.IsZero() {
+		values["until"] = returnDate(away)
+		values["days_left"] = strconv.Itoa(max(0, away.DaysLeft(now)))
+		values["days_left_text"] = ReturnDaysText(away, now)
+	}
+	return values
+}
+
+// withoutPlaceholders is text with its placeholders turned into single words
+// no date is read from, such as "daysleft", so "in {days_left} days" isn't
+// taken for "in days".
+func withoutPlaceholders(text string) string {
+	words := map[string]string{}
+	for _, name := range placeholderNames {
+		words[name] = strings.ReplaceAll(name, "_", "")
+	}
+	if stripped, err := ExpandPlaceholders(text, words); err == nil {
+		return stripped
+	}
+	return text
+}
+
+const placeholderHelp = "You can use {name}, {username}, {until}, {days_left} and {days_left_text} in your text. " +
+	"The last three are filled in from the last return date you sent, like \"Back on Monday\", " +
+	"{days_left_text} reads like \"Back in 3 days\". " +
+	"Write {{name}} to keep a placeholder as it is."
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpandPlaceholders(t *testing.T) {
+	values := map[string]string{"name": "Ann", "username": "@ann", "days_left": "3"}
+
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+		err      string
+	}{
+		{name: "No placeholders", text: "Day off!", expected: "Day off!"},
+		{name: "Name", text: "{name} is away", expected: "Ann is away"},
+		{name: "Several", text: "{username}: back in {days_left} days", expected: "@ann: back in 3 days"},
+		{name: "Next to other text", text: "Hi{name}!", expected: "HiAnn!"},
+		{name: "Escaped placeholder", text: "{{name}} is {name}", expected: "{name} is Ann"},
+		{name: "Braces around a placeholder", text: "{ {name} }", expected: "{ Ann }"},
+		{name: "Unicode around", text: "🏖 {name} в отпуске", expected: "🏖 Ann в отпуске"},
+		{name: "Known without a value", text: "Back {until}", err: "there's nothing to put in {until} yet"},
+		{name: "Unknown words in braces are kept", text: "{-_-} away {until_later}", expected: "{-_-} away {until_later}"},
+		{name: "Unclosed brace is kept", text: "Away {name", expected: "Away {name"},
+		{name: "Stray closing brace is kept", text: "Away :}", expected: "Away :}"},
+		{name: "Doubled braces are kept", text: "{{ off }} {}", expected: "{{ off }} {}"},
+		{name: "Code in braces is kept", text: "func() { return }", expected: "func() { return }"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := ExpandPlaceholders(tt.text, values)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("Expected error %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExpandPlaceholders failed: %v", err)
+			}
+			if actual != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestPlaceholderValues(t *testing.T) {
+	now := time.Date(2026, 10, 17, 15, 0, 0, 0, time.UTC)
+	away := AwayPeriod{
+		Start:    time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC),
+		Back:     time.Date(2026, 10, 25, 0, 0, 0, 0, time.UTC),
+		Language: "ru",
+	}
+
+	tests := []struct {
+		name     string
+		userName string
+		away     AwayPeriod
+		now      time.Time
+		expected map[string]string
+	}{
+		{
+			name:     "Without a return date",
+			userName: "ann",
+			expected: map[string]string{"name": "Ann", "username": "@ann"},
+		},
+		{
+			name:     "Without a username",
+			expected: map[string]string{"name": "Ann", "username": "Ann"},
+		},
+		{
+			name:     "With a return date",
+			userName: "ann",
+			away:     away,
+			now:      now,
+			expected: map[string]string{"name": "Ann", "username": "@ann", "until": "25 октября", "days_left": "8", "days_left_text": "Вернусь через 8 дней"},
+		},
+		{
+			name:     "Fresh values later",
+			userName: "ann",
+			away:     away,
+			now:      now.AddDate(0, 0, 6),
+			expected: map[string]string{"name": "Ann", "username": "@ann", "until": "25 октября", "days_left": "2", "days_left_text": "Вернусь через 2 дня"},
+		},
+		{
+			name:     "Past return date",
+			userName: "ann",
+			away:     away,
+			now:      now.AddDate(0, 1, 0),
+			expected: map[string]string{"name": "Ann", "username": "@ann", "until": "25 октября", "days_left": "0", "days_left_text": "Снова на связи"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := placeholderValues("Ann", tt.userName, tt.away, tt.now)
+			if len(actual) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, actual)
+			}
+			for key, value := range tt.expected {
+				if actual[key] != value {
+					t.Errorf("Expected %s to be %q, got %q", key, value, actual[key])
+				}
+			}
+		})
+	}
+}
+
+func TestWithoutPlaceholders(t *testing.T) {
+	now := time.Date(2026, 10, 17, 15, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{name: "Placeholder words aren't read as dates", text: "Back in {days_left} days"},
+		{name: "Day count text isn't read as a date", text: "{days_left_text}"},
+		{name: "Dates around placeholders are", text: "{name} is back on Monday", expected: "2026-10-19"},
+		{name: "Text that doesn't expand is read as is", text: "Back on Monday :}", expected: "2026-10-19"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			period, ok := ParseReturnDate(withoutPlaceholders(tt.text), now)
+			if tt.expected == "" {
+				if ok {
+					t.Errorf("Expected no date, got %v", period.Back)
+				}
+				return
+			}
+			if !ok || period.Back.Format(time.DateOnly) != tt.expected {
+				t.Errorf("Expected back on %s, got %v, %v", tt.expected, period.Back, ok)
+			}
+		})
+	}
+}
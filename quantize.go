@@ -0,0 +1,128 @@
+package main
+
+import (
+	"image/color"
+	"slices"
+)
+
+// paletteColor is a color picked by medianCut with the number of input colors
+// it stands for.
+type paletteColor struct {
+	Color  color.NRGBA
+	Weight int
+}
+
+// medianCut reduces colors to at most n representatives. The box of colors
+// with the widest channel range is split at its median until there are n
+// boxes, and each box is replaced by its mean. The alpha of the input is
+// ignored and the result is opaque, heaviest colors first.
+func medianCut(colors []color.NRGBA, n int) []paletteColor {
+	if len(colors) == 0 || n <= 0 {
+		return nil
+	}
+
+	boxes := []colorBox{newColorBox(slices.Clone(colors))}
+	for len(boxes) < n {
+		widest := -1
+		for i, box := range boxes {
+			if box.span > 0 && (widest == -1 || box.span > boxes[widest].span) {
+				widest = i
+			}
+		}
+		if widest == -1 {
+			break
+		}
+
+		box := boxes[widest]
+		slices.SortFunc(box.colors, func(a, b color.NRGBA) int {
+			return int(channelValue(a, box.channel)) - int(channelValue(b, box.channel))
+		})
+		middle := medianSplit(box.colors, box.channel)
+		boxes[widest] = newColorBox(box.colors[:middle])
+		boxes = append(boxes, newColorBox(box.colors[middle:]))
+	}
+
+	result := make([]paletteColor, len(boxes))
+	for i, box := range boxes {
+		result[i] = paletteColor{Color: meanColor(box.colors), Weight: len(box.colors)}
+	}
+	slices.SortStableFunc(result, func(a, b paletteColor) int {
+		return b.Weight - a.Weight
+	})
+	return result
+}
+
+// medianSplit returns where to split colors sorted by channel: at the median,
+// moved to the nearest change of value so equal colors stay in one box.
+func medianSplit(colors []color.NRGBA, channel int) int {
+	middle := len(colors) / 2
+	value := channelValue(colors[middle], channel)
+
+	low := middle
+	for low > 0 && channelValue(colors[low-1], channel) == value {
+		low--
+	}
+	high := middle
+	for high < len(colors) && channelValue(colors[high], channel) == value {
+		high++
+	}
+
+	if low > 0 && (high == len(colors) || middle-low <= high-middle) {
+		return low
+	}
+	return high
+}
+
+// colorBox is a group of colors with the channel they vary the most in.
+type colorBox struct {
+	colors  []color.NRGBA
+	channel int
+	span    int
+}
+
+func newColorBox(colors []color.NRGBA) colorBox {
+	channel, span := widestChannel(colors)
+	return colorBox{colors: colors, channel: channel, span: span}
+}
+
+func channelValue(c color.NRGBA, channel int) uint8 {
+	switch channel {
+	case 0:
+		return c.R
+	case 1:
+		return c.G
+	default:
+		return c.B
+	}
+}
+
+func widestChannel(colors []color.NRGBA) (int, int) {
+	widest, widestRange := 0, -1
+	for channel := 0; channel < 3; channel++ {
+		low, high := uint8(255), uint8(0)
+		for _, c := range colors {
+			value := channelValue(c, channel)
+			low, high = min(low, value), max(high, value)
+		}
+		if r := int(high) - int(low); r > widestRange {
+			widest, widestRange = channel, r
+		}
+	}
+	return widest, widestRange
+}
+
+func meanColor(colors []color.NRGBA) color.NRGBA {
+	var r, g, b int
+	for _, c := range colors {
+		r += int(c.R)
+		g += int(c.G)
+		b += int(c.B)
+	}
+	count := len(colors)
+	return color.NRGBA{
+		R: uint8((r + count/2) / count),
+		G: uint8((g + count/2) / count),
+		B: uint8((b + count/2) / count),
+		A: 255,
+	}
+}
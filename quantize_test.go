@@ -0,0 +1,64 @@
+package main
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestMedianCut(t *testing.T) {
+	red := color.NRGBA{R: 250, G: 10, B: 10, A: 255}
+	darkRed := color.NRGBA{R: 230, G: 10, B: 10, A: 255}
+	blue := color.NRGBA{R: 10, G: 10, B: 250, A: 255}
+
+	tests := []struct {
+		name     string
+		colors   []color.NRGBA
+		n        int
+		expected []paletteColor
+	}{
+		{
+			name:     "No colors",
+			n:        4,
+			expected: nil,
+		},
+		{
+			name:     "Fewer distinct colors than wanted",
+			colors:   []color.NRGBA{blue, red, red},
+			n:        4,
+			expected: []paletteColor{{Color: red, Weight: 2}, {Color: blue, Weight: 1}},
+		},
+		{
+			name:     "Close colors are merged",
+			colors:   []color.NRGBA{red, blue, darkRed, blue},
+			n:        2,
+			expected: []paletteColor{{Color: blue, Weight: 2}, {Color: color.NRGBA{R: 240, G: 10, B: 10, A: 255}, Weight: 2}},
+		},
+		{
+			name:     "Equal colors stay together",
+			colors:   []color.NRGBA{red, red, blue, red},
+			n:        2,
+			expected: []paletteColor{{Color: red, Weight: 3}, {Color: blue, Weight: 1}},
+		},
+		{
+			name:     "Alpha is ignored",
+			colors:   []color.NRGBA{{R: 250, G: 10, B: 10, A: 20}},
+			n:        1,
+			expected: []paletteColor{{Color: red, Weight: 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := medianCut(tt.colors, tt.n)
+			if len(actual) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, actual)
+			}
+			for i := range actual {
+				if actual[i] != tt.expected[i] {
+					t.Errorf("Expected %v, got %v", tt.expected, actual)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// AwayPeriod is the time off read from the user's text, from the day it was
+// written to the day they are back, both midnight in the user's timezone.
+// Language is the one the text was written in, "en" or "ru", so the avatar
+// text can be in it too.
+type AwayPeriod struct {
+	Start    time.Time
+	Back     time.Time
+	Language string
+}
+
+func (p AwayPeriod) IsZero() bool {
+	return p.Back.IsZero()
+}
+
+// DaysLeft is the number of days from the day of now until the user is
+// back, counted in the user's timezone.
+func (p AwayPeriod) DaysLeft(now time.Time) int {
+	return daysBetween(startOfDay(now.In(p.Back.Location())), p.Back)
+}
+
+// Dates are read from words and numbers, so "25.10", "2026-10-25", "Oct 25th"
+// and "25 октября" all come in as one or two tokens. Numbers glued to the
+// letters before them, such as "v2.0", stay in one token that is no date.
+var returnTokenPattern = regexp.MustCompile(`\p{L}+\d+(?:[./-]\d+)*|\p{L}+|\d+(?:[./-]\d+)*`)
+
+var returnMonths = map[string]time.Month{
+	"jan": time.January, "january": time.January,
+	"feb": time.February, "february": time.February,
+	"mar": time.March, "march": time.March,
+	"apr": time.April, "april": time.April,
+	"may": time.May,
+	"jun": time.June, "june": time.June,
+	"jul": time.July, "july": time.July,
+	"aug": time.August, "august": time.August,
+	"sep": time.September, "sept": time.September, "september": time.September,
+	"oct": time.October, "october": time.October,
+	"nov": time.November, "november": time.November,
+	"dec": time.December, "december": time.December,
+}
+
+// Russian months are matched as whole words in the cases a date is written
+// in, "25 октября", "в октябре", and by their usual short forms, so words that
+// merely start like a month, such as "марафон", aren't taken for one.
+var russianMonths = map[string]time.Month{
+	"январь": time.January, "января": time.January, "январе": time.January, "янв": time.January,
+	"февраль": time.February, "февраля": time.February, "феврале": time.February, "фев": time.February, "февр": time.February,
+	"март": time.March, "марта": time.March, "марте": time.March, "мар": time.March,
+	"апрель": time.April, "апреля": time.April, "апреле": time.April, "апр": time.April,
+	"май": time.May, "мая": time.May, "мае": time.May,
+	"июнь": time.June, "июня": time.June, "июне": time.June, "июн": time.June,
+	"июль": time.July, "июля": time.July, "июле": time.July, "июл": time.July,
+	"август": time.August, "августа": time.August, "августе": time.August, "авг": time.August,
+	"сентябрь": time.September, "сентября": time.September, "сентябре": time.September, "сен": time.September, "сент": time.September,
+	"октябрь": time.October, "октября": time.October, "октябре": time.October, "окт": time.October,
+	"ноябрь": time.November, "ноября": time.November, "ноябре": time.November, "ноя": time.November, "нояб": time.November,
+	"декабрь": time.December, "декабря": time.December, "декабре": time.December, "дек": time.December,
+}
+
+var returnWeekdays = map[string]time.Weekday{
+	"monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+	"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+	"sunday": time.Sunday,
+
+	"понедельник": time.Monday, "понедельника": time.Monday,
+	"вторник": time.Tuesday, "вторника": time.Tuesday,
+	"среда": time.Wednesday, "среду": time.Wednesday, "среды": time.Wednesday,
+	"четверг": time.Thursday, "четверга": time.Thursday,
+	"пятница": time.Friday, "пятницу": time.Friday, "пятницы": time.Friday,
+	"суббота": time.Saturday, "субботу": time.Saturday, "субботы": time.Saturday,
+	"воскресенье": time.Sunday, "воскресенья": time.Sunday,
+}
+
+// returnShortWeekdays are only taken for a weekday after a return cue, "back
+// wed" or "до пт", as on their own they are often something else. "sat" and
+// "sun" are left out, they are too often about the beach.
+var returnShortWeekdays = map[string]time.Weekday{
+	"mon": time.Monday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wed": time.Wednesday, "thu": time.Thursday, "thur": time.Thursday,
+	"thurs": time.Thursday, "fri": time.Friday,
+
+	"пн": time.Monday, "вт": time.Tuesday, "ср": time.Wednesday, "чт": time.Thursday,
+	"пт": time.Friday, "сб": time.Saturday, "вс": time.Sunday,
+}
+
+// returnDayWords are days counted from today.
+var returnDayWords = map[string]int{
+	"tomorrow":    1,
+	"завтра":      1,
+	"послезавтра": 2,
+}
+
+var returnCountWords = map[string]int{
+	"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5,
+	"six": 6, "seven": 7, "eight": 8, "nine": 9, "ten": 10,
+
+	"один": 1, "одну": 1, "одна": 1, "два": 2, "две": 2, "пару": 2, "три": 3, "четыре": 4,
+	"пять": 5, "шесть": 6, "семь": 7, "восемь": 8, "девять": 9, "десять": 10,
+}
+
+// returnUnit is a length of time off, in days and months.
+type returnUnit struct {
+	days, months int
+}
+
+var returnUnits = map[string]returnUnit{
+	"day": {days: 1}, "days": {days: 1},
+	"week": {days: 7}, "weeks": {days: 7},
+	"month": {months: 1}, "months": {months: 1},
+
+	"день": {days: 1}, "дня": {days: 1}, "дней": {days: 1}, "сутки": {days: 1},
+	"неделя": {days: 7}, "неделю": {days: 7}, "недели": {days: 7}, "недель": {days: 7}, "неделе": {days: 7},
+	"месяц": {months: 1}, "месяца": {months: 1}, "месяцев": {months: 1},
+}
+
+// A unit without a count is one of it after these words, as in "for a week"
+// without the "a" or "на неделю".
+var returnDurationWords = map[string]bool{"for": true, "in": true, "на": true, "через": true}
+
+// returnCueWords tell that a count of days or a short weekday after them is
+// when the user is back, as in "off for 2 weeks" or "back wed", and not the
+// "2 days a week" someone works. returnCueFillers may sit between the cue and
+// the date: "back on wed", "for the next 2 weeks", "вернусь в пн".
+var returnCueWords = map[string]bool{
+	"for": true, "in": true, "until": true, "till": true, "back": true, "by": true, "through": true,
+	"через": true, "на": true, "до": true, "по": true, "вернусь": true, "буду": true,
+}
+
+var returnCueFillers = map[string]bool{"on": true, "the": true, "next": true, "another": true, "в": true, "во": true}
+
+var returnNextWords = map[string]bool{"next": true, "следующей": true, "следующую": true, "следующий": true, "следующем": true}
+
+// Words that can sit between a day and its month: "25th of October", "25-го
+// октября".
+var returnDaySuffixes = map[string]bool{"st": true, "nd": true, "rd": true, "th": true, "of": true, "го": true}
+
+// Counts above maxReturnCount are not time off.
+const maxReturnCount = 999
+
+// ParseReturnDate looks for the day the user is back in text, written as a
+// date, a weekday or a length of time off, in English or Russian. now is the
+// time in the user's timezone, relative dates are counted from its day.
+// Dates without a year are the next ones to come. The first date found in
+// the text is used, ok is false when there is none after today.
+func ParseReturnDate(text string, now time.Time) (AwayPeriod, bool) {
+	today := startOfDay(now)
+	tokens := returnTokenPattern.FindAllString(strings.ToLower(text), -1)
+
+	for i := range tokens {
+		back, ok := parseReturnAt(tokens, i, today)
+		if ok && back.After(today) {
+			return AwayPeriod{Start: today, Back: back, Language: textLanguage(text)}, true
+		}
+	}
+	return AwayPeriod{}, false
+}
+
+// parseReturnAt reads a date starting at the token i.
+func parseReturnAt(tokens []string, i int, today time.Time) (time.Time, bool) {
+	token := tokens[i]
+	at := func(j int) string {
+		if j < 0 || j >= len(tokens) {
+			return ""
+		}
+		return tokens[j]
+	}
+
+	if strings.ContainsAny(token, "./-") {
+		return parseNumericDate(token, today)
+	}
+
+	if day, err := strconv.Atoi(token); err == nil {
+		j := i + 1
+		for returnDaySuffixes[at(j)] {
+			j++
+		}
+		if month, ok := returnMonth(at(j)); ok {
+			return calendarDate(today, 0, month, day)
+		}
+	}
+
+	if month, ok := returnMonth(token); ok {
+		if day, err := strconv.Atoi(at(i + 1)); err == nil {
+			return calendarDate(today, 0, month, day)
+		}
+	}
+
+	if unit, ok := returnUnits[token]; ok {
+		if returnNextWords[at(i-1)] && unit.days == 7 {
+			return nextWeekday(today, time.Monday), true
+		}
+		if returnDurationWords[at(i-1)] {
+			return unit.after(today, 1), true
+		}
+	}
+
+	if count, ok := returnCount(token); ok && returnCued(at, i) {
+		if unit, ok := returnUnits[at(i+1)]; ok {
+			return unit.after(today, count), true
+		}
+	}
+
+	if days, ok := returnDayWords[token]; ok {
+		if at(i-1) == "after" {
+			days++
+		}
+		return today.AddDate(0, 0, days), true
+	}
+
+	if weekday, ok := returnWeekdays[token]; ok {
+		return nextWeekday(today, weekday), true
+	}
+
+	if weekday, ok := returnShortWeekdays[token]; ok && returnCued(at, i) {
+		return nextWeekday(today, weekday), true
+	}
+
+	return time.Time{}, false
+}
+
+// returnCued reports a return cue before the token i, with any fillers
+// between them.
+func returnCued(at func(int) string, i int) bool {
+	j := i - 1
+	for returnCueFillers[at(j)] {
+		j--
+	}
+	return returnCueWords[at(j)]
+}
+
+// parseNumericDate reads day.month, day/month and year-month-day dates, with
+// an optional year of two or four digits after the month. Slashed dates with
+// a month above 12 are read as month/day. Day and month have one or two
+// digits, and the second of them two unless a year follows, so numbers such
+// as "1.2" or "4.5" aren't taken for dates.
+func parseNumericDate(token string, today time.Time) (time.Time, bool) {
+	parts := strings.FieldsFunc(token, func(r rune) bool { return r == '.' || r == '/' || r == '-' })
+	numbers := make([]int, len(parts))
+	for i, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			return time.Time{}, false
+		}
+		numbers[i] = number
+	}
+
+	if len(parts) == 3 && len(parts[0]) == 4 {
+		if len(parts[1]) > 2 || len(parts[2]) > 2 {
+			return time.Time{}, false
+		}
+		return calendarDate(today, numbers[0], time.Month(numbers[1]), numbers[2])
+	}
+	if len(parts) < 2 || len(parts) > 3 || strings.Contains(token, "-") {
+		return time.Time{}, false
+	}
+	if len(parts[0]) > 2 || len(parts[1]) > 2 || len(parts) == 2 && len(parts[1]) != 2 {
+		return time.Time{}, false
+	}
+
+	day, month, year := numbers[0], numbers[1], 0
+	if len(parts) == 3 {
+		year = numbers[2]
+		switch len(parts[2]) {
+		case 2:
+			year += 2000
+		case 4:
+		default:
+			return time.Time{}, false
+		}
+	}
+	if strings.Contains(token, "/") && month > 12 && day <= 12 {
+		day, month = month, day
+	}
+	return calendarDate(today, year, time.Month(month), day)
+}
+
+// calendarDate is the given day in the location of today. Without a year it
+// is the next such day, today included.
+func calendarDate(today time.Time, year int, month time.Month, day int) (time.Time, bool) {
+	guessYear := year == 0
+	if guessYear {
+		year = today.Year()
+	}
+
+	date := time.Date(year, month, day, 0, 0, 0, 0, today.Location())
+	if guessYear && date.Before(today) {
+		year++
+		date = time.Date(year, month, day, 0, 0, 0, 0, today.Location())
+	}
+
+	// time.Date moves the 31st of June and such to the next month.
+	if date.Day() != day || date.Month() != month {
+		return time.Time{}, false
+	}
+	return date, true
+}
+
+func returnMonth(word string) (time.Month, bool) {
+	if month, ok := returnMonths[word]; ok {
+		return month, true
+	}
+	month, ok := russianMonths[word]
+	return month, ok
+}
+
+func returnCount(word string) (int, bool) {
+	if count, ok := returnCountWords[word]; ok {
+		return count, true
+	}
+	count, err := strconv.Atoi(word)
+	if err != nil || count <= 0 || count > maxReturnCount {
+		return 0, false
+	}
+	return count, true
+}
+
+func (u returnUnit) after(day time.Time, count int) time.Time {
+	return day.AddDate(0, u.months*count, u.days*count)
+}
+
+// nextWeekday is the first weekday after day, a week later when day is one.
+func nextWeekday(day time.Time, weekday time.Weekday) time.Time {
+	days := (int(weekday) - int(day.Weekday()) + 7) % 7
+	if days == 0 {
+		days = 7
+	}
+	return day.AddDate(0, 0, days)
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// daysBetween counts calendar days, so days an hour shorter or longer for
+// daylight saving don't throw it off.
+func daysBetween(from, to time.Time) int {
+	fromDay := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	toDay := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(toDay.Sub(fromDay).Hours() / 24)
+}
+
+// textLanguage tells Russian text by its Cyrillic letters, anything else is
+// taken as English.
+func textLanguage(text string) string {
+	for _, r := range text {
+		if unicode.Is(unicode.Cyrillic, r) {
+			return "ru"
+		}
+	}
+	return "en"
+}
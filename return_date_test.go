@@ -0,0 +1,159 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseReturnDate(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("LoadLocation failed: %v", err)
+	}
+	// A Saturday.
+	now := time.Date(2026, 10, 17, 15, 0, 0, 0, berlin)
+
+	tests := []struct {
+		name     string
+		text     string
+		back     string
+		language string
+	}{
+		{name: "Weekday", text: "Back on Monday", back: "2026-10-19", language: "en"},
+		{name: "Same weekday is next week", text: "Back Saturday", back: "2026-10-24", language: "en"},
+		{name: "Day and month", text: "Away until 25.10", back: "2026-10-25", language: "en"},
+		{name: "Past day and month is next year", text: "Away until 10.10", back: "2027-10-10", language: "en"},
+		{name: "Full date", text: "Back 25.10.2027", back: "2027-10-25", language: "en"},
+		{name: "Short year", text: "Back 25/10/26", back: "2026-10-25", language: "en"},
+		{name: "Month first", text: "Back 10/25", back: "2026-10-25", language: "en"},
+		{name: "ISO date", text: "Out until 2026-11-03", back: "2026-11-03", language: "en"},
+		{name: "Month name", text: "Back Oct 25th", back: "2026-10-25", language: "en"},
+		{name: "Day of month name", text: "Back on the 3rd of November", back: "2026-11-03", language: "en"},
+		{name: "Weeks", text: "Off for 2 weeks", back: "2026-10-31", language: "en"},
+		{name: "Days", text: "Back in 3 days", back: "2026-10-20", language: "en"},
+		{name: "A week", text: "Hiking for a week", back: "2026-10-24", language: "en"},
+		{name: "A month", text: "On leave for a month", back: "2026-11-17", language: "en"},
+		{name: "Next week", text: "Back next week", back: "2026-10-19", language: "en"},
+		{name: "Tomorrow", text: "Back tomorrow!", back: "2026-10-18", language: "en"},
+		{name: "Day after tomorrow", text: "Back the day after tomorrow", back: "2026-10-19", language: "en"},
+		{name: "Russian weekday", text: "Вернусь в понедельник", back: "2026-10-19", language: "ru"},
+		{name: "Russian weekday genitive", text: "В отпуске до пятницы", back: "2026-10-23", language: "ru"},
+		{name: "Russian month", text: "До 25 октября", back: "2026-10-25", language: "ru"},
+		{name: "Russian ordinal", text: "Отпуск до 5-го ноября", back: "2026-11-05", language: "ru"},
+		{name: "Russian May", text: "До 1 мая", back: "2027-05-01", language: "ru"},
+		{name: "Russian weeks", text: "Уехал на 2 недели", back: "2026-10-31", language: "ru"},
+		{name: "Russian one week", text: "Вернусь через неделю", back: "2026-10-24", language: "ru"},
+		{name: "Russian next week", text: "Вернусь на следующей неделе", back: "2026-10-19", language: "ru"},
+		{name: "Russian day after tomorrow", text: "Буду послезавтра", back: "2026-10-19", language: "ru"},
+		{name: "No date", text: "Day off!"},
+		{name: "Sun is not Sunday", text: "Enjoying the sun"},
+		{name: "Invalid date", text: "Back 31.02"},
+		{name: "Time is not a date", text: "Back at 10.30"},
+		{name: "Today is not a return", text: "Back 17.10.2026"},
+		{name: "Russian short month", text: "Отпуск до 4 дек", back: "2026-12-04", language: "ru"},
+		{name: "Month with a one-digit day", text: "Back 5.11", back: "2026-11-05", language: "en"},
+		{name: "One-digit month with a year", text: "Back 5.1.2027", back: "2027-01-05", language: "en"},
+		{name: "Word starting like a Russian month", text: "Бегу марафон 5 раз в год"},
+		{name: "Street named after a month", text: "Живу на Октябрьской 12"},
+		{name: "Word starting like an English month", text: "At the market 5 minutes away"},
+		{name: "Decimal number", text: "Version 1.2 is out"},
+		{name: "Decimal number with a one-digit month", text: "Rated 4.5 stars"},
+		{name: "Version number", text: "Updated to v2.0"},
+		{name: "Version number with a two-digit part", text: "Running v3.11 now"},
+		{name: "Three-part version", text: "Release 1.2.3"},
+		{name: "Long numbers", text: "Pi is 3.141"},
+		{name: "Day out of range", text: "Back 32.10"},
+		{name: "Month out of range", text: "Back 12.13"},
+		{name: "Short weekday after a cue", text: "Back wed", back: "2026-10-21", language: "en"},
+		{name: "Short weekday after a cue and a filler", text: "Back on Fri", back: "2026-10-23", language: "en"},
+		{name: "Russian short weekday", text: "В отпуске до пт", back: "2026-10-23", language: "ru"},
+		{name: "Count after fillers", text: "Away for the next 2 weeks", back: "2026-10-31", language: "en"},
+		{name: "Bare short weekday", text: "wed"},
+		{name: "Short weekday without a cue", text: "Team lunch wed"},
+		{name: "Days a week", text: "Working 2 days a week"},
+		{name: "Count without a cue", text: "Took 3 days to finish"},
+		{name: "Russian count without a cue", text: "Раз в 2 недели"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			period, ok := ParseReturnDate(tt.text, now)
+			if tt.back == "" {
+				if ok {
+					t.Fatalf("Expected no date in %q, got %v", tt.text, period.Back)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("Expected a date in %q", tt.text)
+			}
+
+			if back := period.Back.Format(time.DateOnly); back != tt.back {
+				t.Errorf("Expected back on %s, got %s", tt.back, back)
+			}
+			if period.Back.Location() != berlin || period.Back.Hour() != 0 {
+				t.Errorf("Expected midnight in the user's timezone, got %v", period.Back)
+			}
+			if period.Start.Format(time.DateOnly) != "2026-10-17" {
+				t.Errorf("Expected the period to start today, got %v", period.Start)
+			}
+			if period.Language != tt.language {
+				t.Errorf("Expected language %s, got %s", tt.language, period.Language)
+			}
+		})
+	}
+}
+
+func TestParseReturnDateInUserTimezone(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatalf("LoadLocation failed: %v", err)
+	}
+
+	// Still the 17th in UTC, already the 18th in Tokyo.
+	now := time.Date(2026, 10, 17, 20, 0, 0, 0, time.UTC)
+	period, ok := ParseReturnDate("Back tomorrow", now.In(tokyo))
+	if !ok {
+		t.Fatal("Expected a date")
+	}
+	if back := period.Back.Format(time.DateOnly); back != "2026-10-19" {
+		t.Errorf("Expected back on 2026-10-19 in Tokyo, got %s", back)
+	}
+}
+
+func TestAwayPeriodDaysLeft(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("LoadLocation failed: %v", err)
+	}
+	// Clocks go back on the 25th, that day is 25 hours long.
+	period := AwayPeriod{Back: time.Date(2026, 10, 31, 0, 0, 0, 0, berlin)}
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		expected int
+	}{
+		{name: "Two weeks over the clock change", now: time.Date(2026, 10, 17, 23, 59, 0, 0, berlin), expected: 14},
+		{name: "Counted in the user's timezone", now: time.Date(2026, 10, 29, 23, 30, 0, 0, time.UTC), expected: 1},
+		{name: "Day before", now: time.Date(2026, 10, 30, 8, 0, 0, 0, berlin), expected: 1},
+		{name: "Back today", now: time.Date(2026, 10, 31, 8, 0, 0, 0, berlin), expected: 0},
+		{name: "Past", now: time.Date(2026, 11, 2, 8, 0, 0, 0, berlin), expected: -2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := period.DaysLeft(tt.now); actual != tt.expected {
+				t.Errorf("Expected %d days left, got %d", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestTimezoneNames(t *testing.T) {
+	for _, name := range timezoneNames {
+		if _, err := time.LoadLocation(name); err != nil {
+			t.Errorf("Expected the %s timezone to load: %v", name, err)
+		}
+	}
+}
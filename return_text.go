@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+var russianMonthNames = [...]string{
+	"января", "февраля", "марта", "апреля", "мая", "июня",
+	"июля", "августа", "сентября", "октября", "ноября", "декабря",
+}
+
+// ReturnDateText is the avatar text naming the day the user is back, in the
+// language they wrote in: "Back Oct 25" or "Вернусь 25 октября".
+func ReturnDateText(period AwayPeriod) string {
+	if period.Language == "ru" {
+		return "Вернусь " + returnDate(period)
+	}
+	return "Back " + returnDate(period)
+}
+
+// returnDate is the day the user is back as it reads in their language, with
+// the year added when it isn't the current one.
+func returnDate(period AwayPeriod) string {
+	back := period.Back
+	sameYear := back.Year() == period.Start.Year()
+
+	if period.Language == "ru" {
+		date := fmt.Sprintf("%d %s", back.Day(), russianMonthNames[back.Month()-1])
+		if !sameYear {
+			date += fmt.Sprintf(" %d", back.Year())
+		}
+		return date
+	}
+
+	if !sameYear {
+		return back.Format("Jan 2, 2006")
+	}
+	return back.Format("Jan 2")
+}
+
+// ReturnDaysText is the avatar text counting the days until the user is
+// back as of now: "Back in 3 days" or "Вернусь через 3 дня".
+func ReturnDaysText(period AwayPeriod, now time.Time) string {
+	days := period.DaysLeft(now)
+
+	if period.Language == "ru" {
+		switch {
+		case days <= 0:
+			return "Снова на связи"
+		case days == 1:
+			return "Вернусь завтра"
+		}
+		return fmt.Sprintf("Вернусь через %d %s", days, russianPlural(days, "день", "дня", "дней"))
+	}
+
+	switch {
+	case days <= 0:
+		return "Back"
+	case days == 1:
+		return "Back tomorrow"
+	}
+	return fmt.Sprintf("Back in %d days", days)
+}
+
+// returnDaysTemplate is the avatar text counting the days until the user is
+// back. It is filled in from ReturnDaysText at every render, so /refresh keeps
+// both the count and its wording, "Back tomorrow" or "Back", up to date.
+const returnDaysTemplate = "{days_left_text}"
+
+// russianPlural picks the form of a noun counted by n: 1, 21 день, 2, 3, 4
+// дня, 5 to 20 дней.
+func russianPlural(n int, one, few, many string) string {
+	switch {
+	case n%100 >= 11 && n%100 <= 14:
+		return many
+	case n%10 == 1:
+		return one
+	case n%10 >= 2 && n%10 <= 4:
+		return few
+	}
+	return many
+}
+
+// returnSummary spells out the parsed date for the user to check, in English
+// like the rest of the bot: "Sunday, October 25, 2026, 8 days from now".
+func returnSummary(period AwayPeriod, now time.Time) string {
+	summary := period.Back.Format("Monday, January 2, 2006")
+	if days := period.DaysLeft(now); days != 1 {
+		return fmt.Sprintf("%s, %d days from now", summary, days)
+	}
+	return summary + ", tomorrow"
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReturnDateText(t *testing.T) {
+	start := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		back     time.Time
+		language string
+		expected string
+	}{
+		{name: "English", back: time.Date(2026, 10, 25, 0, 0, 0, 0, time.UTC), language: "en", expected: "Back Oct 25"},
+		{name: "English next year", back: time.Date(2027, 1, 4, 0, 0, 0, 0, time.UTC), language: "en", expected: "Back Jan 4, 2027"},
+		{name: "Russian", back: time.Date(2026, 10, 25, 0, 0, 0, 0, time.UTC), language: "ru", expected: "Вернусь 25 октября"},
+		{name: "Russian next year", back: time.Date(2027, 5, 1, 0, 0, 0, 0, time.UTC), language: "ru", expected: "Вернусь 1 мая 2027"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			period := AwayPeriod{Start: start, Back: tt.back, Language: tt.language}
+			if actual := ReturnDateText(period); actual != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestReturnDaysText(t *testing.T) {
+	back := time.Date(2026, 11, 8, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		daysLeft int
+		language string
+		expected string
+	}{
+		{name: "English days", daysLeft: 3, language: "en", expected: "Back in 3 days"},
+		{name: "English tomorrow", daysLeft: 1, language: "en", expected: "Back tomorrow"},
+		{name: "English back", daysLeft: 0, language: "en", expected: "Back"},
+		{name: "Russian few", daysLeft: 3, language: "ru", expected: "Вернусь через 3 дня"},
+		{name: "Russian many", daysLeft: 12, language: "ru", expected: "Вернусь через 12 дней"},
+		{name: "Russian one", daysLeft: 21, language: "ru", expected: "Вернусь через 21 день"},
+		{name: "Russian tomorrow", daysLeft: 1, language: "ru", expected: "Вернусь завтра"},
+		{name: "Russian back", daysLeft: -1, language: "ru", expected: "Снова на связи"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			period := AwayPeriod{Back: back, Language: tt.language}
+			now := back.AddDate(0, 0, -tt.daysLeft).Add(9 * time.Hour)
+			if actual := ReturnDaysText(period, now); actual != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestReturnDaysTemplate(t *testing.T) {
+	start := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+	back := time.Date(2026, 10, 25, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		language string
+		now      time.Time
+		expected string
+	}{
+		{name: "English", language: "en", now: start.Add(15 * time.Hour), expected: "Back in 8 days"},
+		{name: "English days later", language: "en", now: start.AddDate(0, 0, 5), expected: "Back in 3 days"},
+		{name: "English day before", language: "en", now: back.AddDate(0, 0, -1).Add(9 * time.Hour), expected: "Back tomorrow"},
+		{name: "English return day", language: "en", now: back.Add(9 * time.Hour), expected: "Back"},
+		{name: "Russian", language: "ru", now: start.Add(15 * time.Hour), expected: "Вернусь через 8 дней"},
+		{name: "Russian day before", language: "ru", now: back.AddDate(0, 0, -1).Add(9 * time.Hour), expected: "Вернусь завтра"},
+		{name: "Russian return day", language: "ru", now: back.Add(9 * time.Hour), expected: "Снова на связи"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			period := AwayPeriod{Start: start, Back: back, Language: tt.language}
+			actual, err := ExpandPlaceholders(returnDaysTemplate, placeholderValues("Ann", "", period, tt.now))
+			if err != nil {
+				t.Fatalf("ExpandPlaceholders failed: %v", err)
+			}
+			if actual != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, actual)
+			}
+			if button := ReturnDaysText(period, tt.now); actual != button {
+				t.Errorf("Expected the avatar to match the button %q, got %q", button, actual)
+			}
+		})
+	}
+}
+
+func TestRussianPlural(t *testing.T) {
+	tests := []struct {
+		n        int
+		expected string
+	}{
+		{1, "день"}, {2, "дня"}, {4, "дня"}, {5, "дней"}, {11, "дней"}, {12, "дней"},
+		{14, "дней"}, {21, "день"}, {22, "дня"}, {100, "дней"}, {101, "день"}, {111, "дней"},
+	}
+
+	for _, tt := range tests {
+		if actual := russianPlural(tt.n, "день", "дня", "дней"); actual != tt.expected {
+			t.Errorf("Expected %d %s, got %s", tt.n, tt.expected, actual)
+		}
+	}
+}
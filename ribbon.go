@@ -0,0 +1,165 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// Corners of the avatar, as the direction they are in from its center.
+var imageCorners = map[string]image.Point{
+	"top-left":     {X: -1, Y: -1},
+	"top-right":    {X: 1, Y: -1},
+	"bottom-left":  {X: -1, Y: 1},
+	"bottom-right": {X: 1, Y: 1},
+}
+
+const defaultRibbonCorner = "top-right"
+
+// Ribbon size as a fraction of the avatar: its thickness, and how far its
+// middle line is from the corner, measured along the diagonal.
+const (
+	ribbonThickness = 0.12
+	ribbonOffset    = 0.38
+)
+
+// The ribbon holds one line of text, shortened rather than made smaller than
+// 40% of the ribbon.
+var ribbonTextOptions = TextLayoutOptions{
+	MaxLines:    1,
+	MinFontSize: 0.4,
+}
+
+// DrawRibbon paints a ribbon across corner of img, like a sticker, with text
+// along it. The ribbon is filled with the gradient of spec running along its
+// length. It is drawn straight into an offscreen image first and then turned
+// by 45 degrees onto img. For round output the ribbon moves in so it crosses
+// the circle rather than the cut off corner, and the text stays inside it.
+func DrawRibbon(img *image.NRGBA, corner image.Point, text, fontFamily string, style TextStyle, spec GradientSpec, circle bool) error {
+	bounds := img.Bounds()
+	size := float64(min(bounds.Dx(), bounds.Dy()))
+	thickness := math.Round(ribbonThickness * size)
+	offset := ribbonOffset * size
+
+	cornerX, cornerY := float64(bounds.Min.X), float64(bounds.Min.Y)
+	if corner.X > 0 {
+		cornerX = float64(bounds.Max.X)
+	}
+	if corner.Y > 0 {
+		cornerY = float64(bounds.Max.Y)
+	}
+
+	centerX, centerY, radius := inscribedCircle(bounds)
+	cornerDistance := math.Hypot(cornerX-centerX, cornerY-centerY)
+	if circle {
+		offset += cornerDistance - radius
+	}
+
+	// The edge nearer the corner is the shorter one, the text has to fit
+	// along it. In the square it cuts off a right triangle, so it is twice
+	// as long as its distance from the corner.
+	innerOffset := offset - thickness/2
+	textWidth := 2 * innerOffset
+	if circle {
+		textWidth = min(textWidth, chordWidth(radius*(1-circleSafeMargin), cornerDistance-innerOffset))
+	}
+
+	// Long enough to run off the avatar at both ends.
+	length := math.Ceil(2 * (offset + thickness/2))
+	ribbon := CreateGradient(int(length), int(thickness), spec)
+
+	textRect := image.Rect(
+		int((length-textWidth)/2), 0,
+		int((length+textWidth)/2), int(thickness),
+	)
+	if textRect.Dx() > 0 {
+		if _, err := DrawTextToImage(ribbon.SubImage(textRect).(*image.NRGBA), text, fontFamily, style, ribbonTextOptions); err != nil {
+			return err
+		}
+	}
+
+	// The ribbon runs from one edge at the corner to the other, text
+	// reading left to right, so it goes down the avatar on the top-right
+	// and bottom-left and up it on the other two.
+	angle := 45.0
+	if corner.X == corner.Y {
+		angle = -45
+	}
+	step := offset / math.Sqrt2
+	DrawRotated(img, ribbon, cornerX-float64(corner.X)*step, cornerY-float64(corner.Y)*step, angle)
+
+	return nil
+}
+
+// DrawRotated paints src over dst turned by degrees clockwise around its
+// center, which lands on x, y of dst. Every pixel of dst is sampled back from
+// src bilinearly, so the turned edges come out antialiased.
+func DrawRotated(dst, src *image.NRGBA, x, y, degrees float64) {
+	srcBounds := src.Bounds()
+	halfWidth, halfHeight := float64(srcBounds.Dx())/2, float64(srcBounds.Dy())/2
+	sin, cos := math.Sincos(degrees * math.Pi / 180)
+
+	extentX := math.Abs(halfWidth*cos) + math.Abs(halfHeight*sin) + 1
+	extentY := math.Abs(halfWidth*sin) + math.Abs(halfHeight*cos) + 1
+	area := image.Rect(
+		int(math.Floor(x-extentX)), int(math.Floor(y-extentY)),
+		int(math.Ceil(x+extentX)), int(math.Ceil(y+extentY)),
+	).Intersect(dst.Bounds())
+
+	for py := area.Min.Y; py < area.Max.Y; py++ {
+		for px := area.Min.X; px < area.Max.X; px++ {
+			dx, dy := float64(px)+0.5-x, float64(py)+0.5-y
+			u := dx*cos + dy*sin + halfWidth
+			v := -dx*sin + dy*cos + halfHeight
+
+			c, ok := sampleBilinear(src, u, v)
+			if !ok {
+				continue
+			}
+			dst.SetNRGBA(px, py, CompositeOver(dst.NRGBAAt(px, py), c, 0xffff))
+		}
+	}
+}
+
+// sampleBilinear returns the color of img at u, v, in pixels from the
+// top-left corner of its bounds. Colors are mixed premultiplied and outside
+// img counts as transparent, so edges fade out over a pixel. ok is false
+// when nothing of img is there.
+func sampleBilinear(img *image.NRGBA, u, v float64) (color.NRGBA, bool) {
+	bounds := img.Bounds()
+	u, v = u-0.5, v-0.5
+	x0, y0 := int(math.Floor(u)), int(math.Floor(v))
+	fx, fy := u-float64(x0), v-float64(y0)
+
+	var r, g, b, a float64
+	for _, tap := range [4]struct {
+		x, y   int
+		weight float64
+	}{
+		{x0, y0, (1 - fx) * (1 - fy)},
+		{x0 + 1, y0, fx * (1 - fy)},
+		{x0, y0 + 1, (1 - fx) * fy},
+		{x0 + 1, y0 + 1, fx * fy},
+	} {
+		point := image.Pt(bounds.Min.X+tap.x, bounds.Min.Y+tap.y)
+		if tap.weight == 0 || !point.In(bounds) {
+			continue
+		}
+		pixel := img.NRGBAAt(point.X, point.Y)
+		alpha := float64(pixel.A) * tap.weight
+		r += float64(pixel.R) * alpha
+		g += float64(pixel.G) * alpha
+		b += float64(pixel.B) * alpha
+		a += alpha
+	}
+
+	if a < 0.5 {
+		return color.NRGBA{}, false
+	}
+	return color.NRGBA{
+		R: uint8(math.Round(r / a)),
+		G: uint8(math.Round(g / a)),
+		B: uint8(math.Round(b / a)),
+		A: uint8(math.Round(a)),
+	}, true
+}
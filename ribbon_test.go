@@ -0,0 +1,139 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestSampleBilinear(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	img.SetNRGBA(0, 0, color.NRGBA{R: 255, A: 255})
+	img.SetNRGBA(1, 0, color.NRGBA{B: 255, A: 0})
+
+	tests := []struct {
+		name     string
+		u, v     float64
+		expected color.NRGBA
+		ok       bool
+	}{
+		{name: "Pixel center", u: 0.5, v: 0.5, expected: color.NRGBA{R: 255, A: 255}, ok: true},
+		{name: "Transparent neighbour keeps the color", u: 1, v: 0.5, expected: color.NRGBA{R: 255, A: 128}, ok: true},
+		{name: "Edge fades out", u: 0.5, v: 1, expected: color.NRGBA{R: 255, A: 128}, ok: true},
+		{name: "Outside", u: -1, v: 0.5, ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, ok := sampleBilinear(img, tt.u, tt.v)
+			if ok != tt.ok || actual != tt.expected {
+				t.Errorf("Expected %v, %v, got %v, %v", tt.expected, tt.ok, actual, ok)
+			}
+		})
+	}
+}
+
+func TestDrawRotated(t *testing.T) {
+	// A bar with a red left half and a blue right half.
+	red, blue := color.NRGBA{R: 255, A: 255}, color.NRGBA{B: 255, A: 255}
+	src := image.NewNRGBA(image.Rect(0, 0, 40, 10))
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 40; x++ {
+			if x < 20 {
+				src.SetNRGBA(x, y, red)
+			} else {
+				src.SetNRGBA(x, y, blue)
+			}
+		}
+	}
+
+	tests := []struct {
+		name                string
+		degrees             float64
+		redPoint, bluePoint image.Point
+		empty               image.Point
+	}{
+		{name: "Unturned", degrees: 0, redPoint: image.Pt(35, 50), bluePoint: image.Pt(65, 50), empty: image.Pt(50, 30)},
+		{name: "Clockwise", degrees: 90, redPoint: image.Pt(50, 35), bluePoint: image.Pt(50, 65), empty: image.Pt(30, 50)},
+		{name: "Counterclockwise", degrees: -90, redPoint: image.Pt(50, 65), bluePoint: image.Pt(50, 35), empty: image.Pt(30, 50)},
+		{name: "Diagonal", degrees: 45, redPoint: image.Pt(40, 40), bluePoint: image.Pt(60, 60), empty: image.Pt(60, 40)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := solidImage(100, white)
+			DrawRotated(dst, src, 50, 50, tt.degrees)
+
+			if actual := dst.NRGBAAt(tt.redPoint.X, tt.redPoint.Y); actual != red {
+				t.Errorf("Expected red at %v, got %v", tt.redPoint, actual)
+			}
+			if actual := dst.NRGBAAt(tt.bluePoint.X, tt.bluePoint.Y); actual != blue {
+				t.Errorf("Expected blue at %v, got %v", tt.bluePoint, actual)
+			}
+			if actual := dst.NRGBAAt(tt.empty.X, tt.empty.Y); actual != white {
+				t.Errorf("Expected nothing drawn at %v, got %v", tt.empty, actual)
+			}
+		})
+	}
+}
+
+func TestDrawRibbon(t *testing.T) {
+	background := color.NRGBA{R: 40, G: 40, B: 40, A: 255}
+	spec := GradientSpec{
+		Shape: LinearGradient(90),
+		Stops: []GradientStop{{Position: 0, Color: color.NRGBA{R: 200, A: 255}}, {Position: 1, Color: color.NRGBA{R: 200, A: 255}}},
+	}
+
+	tests := []struct {
+		name   string
+		corner string
+		// A point on the middle line of the ribbon and one past it, in the
+		// very corner.
+		ribbon, clear image.Point
+	}{
+		{name: "Top left", corner: "top-left", ribbon: image.Pt(80, 80), clear: image.Pt(5, 5)},
+		{name: "Top right", corner: "top-right", ribbon: image.Pt(219, 80), clear: image.Pt(294, 5)},
+		{name: "Bottom left", corner: "bottom-left", ribbon: image.Pt(80, 219), clear: image.Pt(5, 294)},
+		{name: "Bottom right", corner: "bottom-right", ribbon: image.Pt(219, 219), clear: image.Pt(294, 294)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := solidImage(300, background)
+			if err := DrawRibbon(img, imageCorners[tt.corner], "", defaultFontFamily, textStyles["plain"], spec, false); err != nil {
+				t.Fatalf("DrawRibbon failed: %v", err)
+			}
+
+			if actual := img.NRGBAAt(tt.ribbon.X, tt.ribbon.Y); actual != spec.Stops[0].Color {
+				t.Errorf("Expected the ribbon at %v, got %v", tt.ribbon, actual)
+			}
+			if actual := img.NRGBAAt(tt.clear.X, tt.clear.Y); actual != background {
+				t.Errorf("Expected the corner %v left clear, got %v", tt.clear, actual)
+			}
+			if actual := img.NRGBAAt(150, 150); actual != background {
+				t.Errorf("Expected the middle left clear, got %v", actual)
+			}
+		})
+	}
+}
+
+func TestDrawRibbonInsideCircle(t *testing.T) {
+	background := color.NRGBA{R: 40, G: 40, B: 40, A: 255}
+	img := solidImage(300, background)
+	if err := DrawRibbon(img, imageCorners["top-right"], "Away", defaultFontFamily, textStyles["plain"], GradientSpec{
+		Shape: LinearGradient(90),
+		Stops: gradientPalettes[defaultGradientPalette],
+	}, true); err != nil {
+		t.Fatalf("DrawRibbon failed: %v", err)
+	}
+
+	// The middle line of the ribbon crosses the diagonal inside the circle,
+	// ribbonOffset of the avatar in from where the circle meets it.
+	centerX, centerY, radius := inscribedCircle(img.Bounds())
+	step := (radius - ribbonOffset*300) / math.Sqrt2
+	x, y := int(centerX+step), int(centerY-step)
+	if actual := img.NRGBAAt(x, y); actual == background {
+		t.Errorf("Expected the ribbon at %d, %d inside the circle", x, y)
+	}
+}
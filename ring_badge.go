@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"io/fs"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// RingBadge is a band along the edge of the avatar circle with text following
+// the curve, like the LinkedIn #OpenToWork frame. Angles are in degrees with
+// the conic gradient convention: 0 points straight down and 90 to the right.
+// The band spans Sweep degrees around Center, Width is a fraction of the
+// radius and Stops color the band from its start to its end.
+type RingBadge struct {
+	Text       string
+	FontFamily string
+	TextColor  color.NRGBA
+	Stops      []GradientStop
+	Center     float64
+	Sweep      float64
+	Width      float64
+}
+
+// ringBadgeFile is a badge as it is written in the JSON files in ringBadgeDir
+// of the assets, with colors as "#rrggbb" or "#rrggbbaa". Operators add
+// badges by dropping a file into the override directory.
+type ringBadgeFile struct {
+	Name      string `json:"name"`
+	Text      string `json:"text"`
+	Font      string `json:"font"`
+	TextColor string `json:"text_color"`
+	Stops     []struct {
+		Position float64 `json:"position"`
+		Color    string  `json:"color"`
+	} `json:"stops"`
+	Center float64 `json:"center"`
+	Sweep  float64 `json:"sweep"`
+	Width  float64 `json:"width"`
+}
+
+const ringBadgeDir = "badges"
+
+var (
+	ringBadges     = map[string]RingBadge{defaultRingBadge: {}}
+	ringBadgeNames = []string{defaultRingBadge}
+)
+
+const defaultRingBadge = "none"
+
+// LoadRingBadges reads and validates every badge in the assets. The
+// built-in "none" is listed first, the others in file name order.
+func LoadRingBadges() error {
+	badges := map[string]RingBadge{defaultRingBadge: {}}
+	names := []string{defaultRingBadge}
+
+	for _, file := range assetFiles(ringBadgeDir, "*.json") {
+		name, badge, err := loadRingBadge(file)
+		if err != nil {
+			return err
+		}
+		if _, exists := badges[name]; exists {
+			return fmt.Errorf("%s: badge %q is already defined", file, name)
+		}
+
+		badges[name] = badge
+		names = append(names, name)
+	}
+
+	ringBadges = badges
+	ringBadgeNames = names
+	return nil
+}
+
+func loadRingBadge(file string) (string, RingBadge, error) {
+	data, err := fs.ReadFile(assets, file)
+	if err != nil {
+		return "", RingBadge{}, fmt.Errorf("error reading badge: %v", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var badgeFile ringBadgeFile
+	if err := decoder.Decode(&badgeFile); err != nil {
+		return "", RingBadge{}, fmt.Errorf("%s: error parsing badge: %v", file, err)
+	}
+	badge, err := badgeFile.ringBadge()
+	if err != nil {
+		return "", RingBadge{}, fmt.Errorf("%s: %v", file, err)
+	}
+	return badgeFile.Name, badge, nil
+}
+
+// ringBadge validates the badge and converts it for drawing. The font
+// defaults to the default family and the text color to white.
+func (f ringBadgeFile) ringBadge() (RingBadge, error) {
+	if !templateNamePattern.MatchString(f.Name) {
+		return RingBadge{}, fmt.Errorf("badge name %q must be 1 to 32 lowercase letters, digits or dashes", f.Name)
+	}
+	if f.Sweep <= 0 || f.Sweep > 360 {
+		return RingBadge{}, fmt.Errorf("sweep must be between 0 and 360 degrees, got %g", f.Sweep)
+	}
+	if f.Width <= 0 || f.Width > 1 {
+		return RingBadge{}, fmt.Errorf("width must be between 0 and 1, got %g", f.Width)
+	}
+	if len(f.Stops) < 2 {
+		return RingBadge{}, fmt.Errorf("badge %s needs at least two color stops", f.Name)
+	}
+
+	badge := RingBadge{
+		Text:       f.Text,
+		FontFamily: f.Font,
+		TextColor:  textColor,
+		Center:     f.Center,
+		Sweep:      f.Sweep,
+		Width:      f.Width,
+	}
+	if badge.FontFamily == "" {
+		badge.FontFamily = defaultFontFamily
+	}
+	if !hasKey(fontFamilies, badge.FontFamily) {
+		return RingBadge{}, fmt.Errorf("unknown font %q", badge.FontFamily)
+	}
+	if f.TextColor != "" {
+		var err error
+		if badge.TextColor, err = parseHexColor(f.TextColor); err != nil {
+			return RingBadge{}, fmt.Errorf("text_color: %v", err)
+		}
+	}
+
+	for i, stop := range f.Stops {
+		if stop.Position < 0 || stop.Position > 1 || (i > 0 && stop.Position < f.Stops[i-1].Position) {
+			return RingBadge{}, fmt.Errorf("stop %d: positions must go up from 0 to 1", i+1)
+		}
+		stopColor, err := parseHexColor(stop.Color)
+		if err != nil {
+			return RingBadge{}, fmt.Errorf("stop %d: %v", i+1, err)
+		}
+		badge.Stops = append(badge.Stops, GradientStop{Position: stop.Position, Color: stopColor})
+	}
+	return badge, nil
+}
+
+// parseHexColor reads "#rrggbb", opaque, or "#rrggbbaa".
+func parseHexColor(s string) (color.NRGBA, error) {
+	digits, ok := strings.CutPrefix(s, "#")
+	if !ok || (len(digits) != 6 && len(digits) != 8) {
+		return color.NRGBA{}, fmt.Errorf("color %q must be #rrggbb or #rrggbbaa", s)
+	}
+	if len(digits) == 6 {
+		digits += "ff"
+	}
+
+	value, err := strconv.ParseUint(digits, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("color %q must be #rrggbb or #rrggbbaa", s)
+	}
+	return color.NRGBA{R: uint8(value >> 24), G: uint8(value >> 16), B: uint8(value >> 8), A: uint8(value)}, nil
+}
+
+// ringTextFill is the share of the band arc the text may take up.
+const ringTextFill = 0.8
+
+func arcPoint(centerX, centerY, radius, angle float64) (float64, float64) {
+	return centerX + radius*math.Sin(angle), centerY + radius*math.Cos(angle)
+}
+
+// DrawRingBadge draws the band along the inscribed circle of img and the
+// badge text along it.
+func DrawRingBadge(img *image.NRGBA, badge RingBadge) error {
+	if badge.Sweep <= 0 || badge.Width <= 0 {
+		return nil
+	}
+
+	bounds := img.Bounds()
+	centerX, centerY, radius := inscribedCircle(bounds)
+	width := badge.Width * radius
+	start := (badge.Center - badge.Sweep/2) * math.Pi / 180
+	sweep := badge.Sweep * math.Pi / 180
+
+	drawRingBand(img, badge, centerX, centerY, radius, width, start, sweep)
+
+	if badge.Text == "" {
+		return nil
+	}
+
+	ttfFont, err := CachedLoadFontFamily(badge.FontFamily)
+	if err != nil {
+		return err
+	}
+	mask := image.NewAlpha(bounds)
+	drawArcText(mask, ttfFont, badge.Text, centerX, centerY, radius-width, width, start+sweep/2, sweep*ringTextFill)
+	FillMask(img, mask, badge.TextColor)
+
+	return nil
+}
+
+// drawRingBand fills the arc of the band with a conic gradient, so the stops
+// follow the band around the circle.
+func drawRingBand(img *image.NRGBA, badge RingBadge, centerX, centerY, radius, width, start, sweep float64) {
+	bounds := img.Bounds()
+
+	stops := make([]GradientStop, len(badge.Stops))
+	for i, stop := range badge.Stops {
+		stops[i] = GradientStop{Position: stop.Position * sweep / (2 * math.Pi), Color: stop.Color}
+	}
+	shape := ConicGradient(
+		(centerX-float64(bounds.Min.X))/float64(bounds.Dx()),
+		(centerY-float64(bounds.Min.Y))/float64(bounds.Dy()),
+		start*180/math.Pi,
+	)
+	fill := CachedCreateGradient(bounds.Dx(), bounds.Dy(), GradientSpec{Shape: shape, Stops: stops, Space: ColorSpaceOKLab})
+
+	band := image.NewAlpha(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			coverage := circleCoverage(x, y, centerX, centerY, radius) *
+				(1 - circleCoverage(x, y, centerX, centerY, radius-width))
+			if coverage == 0 {
+				continue
+			}
+
+			dx, dy := float64(x)+0.5-centerX, float64(y)+0.5-centerY
+			angle := math.Mod(math.Atan2(dx, dy)-start, 2*math.Pi)
+			if angle < 0 {
+				angle += 2 * math.Pi
+			}
+
+			// Antialias the ends of the arc by the distance to them in pixels.
+			distance := math.Hypot(dx, dy)
+			coverage *= clampUnit(math.Min(angle, sweep-angle)*distance + 0.5)
+			band.SetAlpha(x, y, color.Alpha{A: uint8(math.Round(coverage * 255))})
+		}
+	}
+
+	FillMaskImage(img, band, fill)
+}
+
+// drawArcText lays text along the circle of innerRadius + width, upright at
+// the bottom and centered on angle. Each glyph is rotated to the tangent at
+// its middle, with its caps centered in the band. Text is shrunk when it
+// would be longer than maxSweep.
+func drawArcText(mask *image.Alpha, ttfFont *FontCache, text string, centerX, centerY, innerRadius, width, angle, maxSweep float64) {
+	fontSize := width * 0.5
+	face, err := ttfFont.NewFace(fontSize)
+	if err != nil {
+		return
+	}
+	capHeight := float64(face.Metrics().CapHeight) / 64
+	face.Close()
+
+	baseline := innerRadius + (width+capHeight)/2
+	line := ttfFont.shapeLine(text, fontSize)
+	if length := float64(line.advance) / 64; length > maxSweep*baseline {
+		fontSize *= maxSweep * baseline / length
+		capHeight *= maxSweep * baseline / length
+		baseline = innerRadius + (width+capHeight)/2
+		line = ttfFont.shapeLine(text, fontSize)
+	}
+
+	length := float64(line.advance) / 64
+
+	var buf sfnt.Buffer
+	ppem := fixed.Int26_6(fontSize * 64)
+	for _, glyph := range line.glyphs {
+		segments, err := ttfFont.fonts[glyph.font].LoadGlyph(&buf, glyph.id, ppem, nil)
+		if err != nil {
+			continue
+		}
+
+		halfAdvance := float64(glyph.advance) / 128
+		middle := float64(glyph.x)/64 + halfAdvance
+		glyphAngle := angle + (middle-length/2)/baseline
+
+		middleX, middleY := arcPoint(centerX, centerY, baseline, glyphAngle)
+		tangentX, tangentY := math.Cos(glyphAngle), -math.Sin(glyphAngle)
+		outwardX, outwardY := math.Sin(glyphAngle), math.Cos(glyphAngle)
+		offsetY := float64(glyph.y) / 64
+
+		drawGlyphSegments(mask, segments, func(x, y float64) (float64, float64) {
+			x -= halfAdvance
+			y += offsetY
+			return middleX + x*tangentX + y*outwardX, middleY + x*tangentY + y*outwardY
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"image/color"
+	"math"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadRingBadges(t *testing.T) {
+	if err := LoadRingBadges(); err != nil {
+		t.Fatalf("LoadRingBadges failed: %v", err)
+	}
+
+	expected := []string{"none", "opentowork", "outofoffice", "vacation"}
+	if !slices.Equal(ringBadgeNames, expected) {
+		t.Errorf("Expected %v, got %v", expected, ringBadgeNames)
+	}
+
+	badge := ringBadges["opentowork"]
+	green := color.NRGBA{R: 68, G: 113, B: 47, A: 255}
+	if badge.Text != "#OPENTOWORK" || badge.FontFamily != "bold" || badge.TextColor != textColor || badge.Stops[1].Color != green {
+		t.Errorf("Unexpected opentowork badge %+v", badge)
+	}
+}
+
+func TestLoadRingBadgesFromOverride(t *testing.T) {
+	originalAssets := assets
+	defer func() {
+		assets = originalAssets
+		LoadRingBadges()
+	}()
+
+	stops := `"stops": [{"position": 0, "color": "#ff0000"}, {"position": 1, "color": "#0000ff80"}]`
+	tests := []struct {
+		name     string
+		files    map[string]string
+		expected string
+	}{
+		{
+			name:  "Added badge",
+			files: map[string]string{"badges/hiring.json": `{"name": "hiring", "text": "#HIRING", ` + stops + `, "sweep": 180, "width": 0.2}`},
+		},
+		{
+			name:     "Duplicate name",
+			files:    map[string]string{"badges/vacation2.json": `{"name": "vacation", ` + stops + `, "sweep": 180, "width": 0.2}`},
+			expected: `badges/vacation2.json: badge "vacation" is already defined`,
+		},
+		{
+			name:     "No badge is reserved",
+			files:    map[string]string{"badges/none.json": `{"name": "none", ` + stops + `, "sweep": 180, "width": 0.2}`},
+			expected: `badges/none.json: badge "none" is already defined`,
+		},
+		{
+			name:     "Unknown field",
+			files:    map[string]string{"badges/typo.json": `{"name": "typo", "colour": "#ffffff"}`},
+			expected: `badges/typo.json: error parsing badge: json: unknown field "colour"`,
+		},
+		{
+			name:     "Bad color",
+			files:    map[string]string{"badges/red.json": `{"name": "red", "text_color": "red", ` + stops + `, "sweep": 180, "width": 0.2}`},
+			expected: `badges/red.json: text_color: color "red" must be #rrggbb or #rrggbbaa`,
+		},
+		{
+			name:     "Unknown font",
+			files:    map[string]string{"badges/comic.json": `{"name": "comic", "font": "comic", ` + stops + `, "sweep": 180, "width": 0.2}`},
+			expected: `badges/comic.json: unknown font "comic"`,
+		},
+		{
+			name:     "Stops out of order",
+			files:    map[string]string{"badges/back.json": `{"name": "back", "stops": [{"position": 1, "color": "#ff0000"}, {"position": 0, "color": "#0000ff"}], "sweep": 180, "width": 0.2}`},
+			expected: `badges/back.json: stop 2: positions must go up from 0 to 1`,
+		},
+		{
+			name:     "No sweep",
+			files:    map[string]string{"badges/flat.json": `{"name": "flat", ` + stops + `, "width": 0.2}`},
+			expected: `badges/flat.json: sweep must be between 0 and 360 degrees, got 0`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			override := fstest.MapFS{}
+			for name, data := range tt.files {
+				override[name] = &fstest.MapFile{Data: []byte(data)}
+			}
+			assets = overlayFS{override, originalAssets}
+
+			err := LoadRingBadges()
+			if tt.expected == "" {
+				if err != nil {
+					t.Fatalf("LoadRingBadges failed: %v", err)
+				}
+				if !slices.Contains(ringBadgeNames, "hiring") || ringBadges["hiring"].Stops[1].Color.A != 128 {
+					t.Errorf("Expected the hiring badge to be added, got %v", ringBadgeNames)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.expected {
+				t.Errorf("Expected error %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestDrawRingBadgeBand(t *testing.T) {
+	background := color.NRGBA{R: 150, G: 150, B: 170, A: 255}
+	green := color.NRGBA{R: 68, G: 113, B: 47, A: 255}
+
+	if err := LoadRingBadges(); err != nil {
+		t.Fatalf("LoadRingBadges failed: %v", err)
+	}
+
+	img := solidImage(200, background)
+	badge := ringBadges["opentowork"]
+	badge.Text = ""
+	if err := DrawRingBadge(img, badge); err != nil {
+		t.Fatalf("DrawRingBadge failed: %v", err)
+	}
+
+	centerX, centerY, radius := inscribedCircle(img.Bounds())
+	at := func(angle, distance float64) color.NRGBA {
+		x, y := arcPoint(centerX, centerY, distance, angle*math.Pi/180)
+		return img.NRGBAAt(int(x), int(y))
+	}
+
+	tests := []struct {
+		name     string
+		angle    float64
+		distance float64
+		expected color.NRGBA
+	}{
+		{name: "Middle of the band is filled", angle: -50, distance: radius * 0.9, expected: green},
+		{name: "Inside the band is untouched", angle: -50, distance: radius * 0.7, expected: background},
+		{name: "Outside the sweep is untouched", angle: 180, distance: radius * 0.9, expected: background},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := at(tt.angle, tt.distance); actual != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+
+	if faded := at(-145, radius*0.9); faded == green || faded == background {
+		t.Errorf("Expected the start of the band to fade in, got %v", faded)
+	}
+}
+
+func TestDrawRingBadgeText(t *testing.T) {
+	if err := LoadRingBadges(); err != nil {
+		t.Fatalf("LoadRingBadges failed: %v", err)
+	}
+
+	img := solidImage(300, color.NRGBA{R: 0, G: 0, B: 0, A: 255})
+	badge := ringBadges["opentowork"]
+	badge.Stops = nil
+	if err := DrawRingBadge(img, badge); err != nil {
+		t.Fatalf("DrawRingBadge failed: %v", err)
+	}
+
+	centerX, centerY, radius := inscribedCircle(img.Bounds())
+	inner := radius * (1 - badge.Width)
+	drawn := 0
+	for y := 0; y < 300; y++ {
+		for x := 0; x < 300; x++ {
+			if img.NRGBAAt(x, y).R == 0 {
+				continue
+			}
+			drawn++
+			distance := math.Hypot(float64(x)+0.5-centerX, float64(y)+0.5-centerY)
+			if distance < inner-3 || distance > radius+1 {
+				t.Fatalf("Text pixel (%d,%d) is outside the band", x, y)
+			}
+			if x > int(centerX)+int(radius/2) {
+				t.Fatalf("Text pixel (%d,%d) is outside the badge arc", x, y)
+			}
+		}
+	}
+	if drawn == 0 {
+		t.Error("Expected the badge text to be drawn")
+	}
+}
+
+func TestDrawRingBadgeNone(t *testing.T) {
+	background := color.NRGBA{R: 10, G: 20, B: 30, A: 255}
+	img := solidImage(100, background)
+	if err := DrawRingBadge(img, ringBadges["none"]); err != nil {
+		t.Fatalf("DrawRingBadge failed: %v", err)
+	}
+	if !CompareImages(img, solidImage(100, background)) {
+		t.Error("Expected no badge to leave the image untouched")
+	}
+}
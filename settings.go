@@ -0,0 +1,106 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	// Timezones come with the binary, the host may have no zoneinfo.
+	_ "time/tzdata"
+)
+
+type UserSettings struct {
+	GradientShape GradientShape
+	Palette       string
+	ColorSpace    ColorSpace
+	BlendMode     BlendMode
+	TextStyle     TextStyle
+	TextPosition  TextPosition
+	FontFamily    string
+	OutputShape   OutputShape
+	RingBadge     RingBadge
+	Template      string
+	Animation     AnimationEffect
+	Timezone      string
+	// Away is the last time off read from the user's text, PendingText the
+	// text itself while they pick what the avatar should say.
+	Away        AwayPeriod
+	PendingText string
+	// TextTemplate is the last text rendered, placeholders and all, for
+	// /refresh to fill in again.
+	TextTemplate string
+}
+
+func defaultUserSettings() UserSettings {
+	return UserSettings{
+		GradientShape: defaultGradientShape,
+		Palette:       defaultGradientPalette,
+		ColorSpace:    colorSpaces[defaultColorSpace],
+		BlendMode:     BlendNormal,
+		TextStyle:     textStyles["shadow"],
+		TextPosition:  textPositions[defaultTextPosition],
+		FontFamily:    defaultFontFamily,
+		OutputShape:   outputShapes[defaultOutputShape],
+		RingBadge:     ringBadges[defaultRingBadge],
+		Template:      defaultTemplate,
+		Animation:     animationEffects[defaultAnimationEffect],
+		Timezone:      defaultTimezone,
+	}
+}
+
+func (s UserSettings) GradientSpec() GradientSpec {
+	stops, ok := gradientPalettes[s.Palette]
+	if !ok {
+		stops = gradientPalettes[defaultGradientPalette]
+	}
+	return GradientSpec{Shape: s.GradientShape, Stops: stops, Space: s.ColorSpace}
+}
+
+// Location is the user's timezone, dates in their text are read in it.
+func (s UserSettings) Location() *time.Location {
+	location, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// timezoneNames are offered in the timezone menu, any other IANA name can be
+// typed after the command.
+var timezoneNames = []string{
+	"UTC", "Europe/London", "Europe/Berlin", "Europe/Kyiv", "Europe/Moscow", "Asia/Dubai",
+	"Asia/Almaty", "Asia/Kolkata", "Asia/Singapore", "Asia/Tokyo", "Australia/Sydney",
+	"America/Sao_Paulo", "America/New_York", "America/Chicago", "America/Los_Angeles",
+}
+
+const defaultTimezone = "UTC"
+
+type SettingsStore struct {
+	mu       sync.Mutex
+	settings map[int64]UserSettings
+}
+
+func NewSettingsStore() *SettingsStore {
+	return &SettingsStore{settings: map[int64]UserSettings{}}
+}
+
+func (s *SettingsStore) Get(userId int64) UserSettings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if settings, ok := s.settings[userId]; ok {
+		return settings
+	}
+	return defaultUserSettings()
+}
+
+func (s *SettingsStore) Update(userId int64, update func(settings *UserSettings)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	settings, ok := s.settings[userId]
+	if !ok {
+		settings = defaultUserSettings()
+	}
+	update(&settings)
+	s.settings[userId] = settings
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strconv"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// SignatureConfig is the mark left on every avatar. Image is an asset, such
+// as a logo, drawn instead of the text. Scale is the height of the signature
+// as a fraction of the avatar, so it looks the same at any resolution.
+type SignatureConfig struct {
+	Disabled bool
+	Text     string
+	Image    string
+	Corner   string
+	Scale    float64
+	Opacity  float64
+}
+
+var defaultSignatureConfig = SignatureConfig{
+	Text:    "@VacatoBot",
+	Corner:  "bottom-right",
+	Scale:   0.035,
+	Opacity: 1,
+}
+
+// signatureConfig is the deployment's signature, set up once at start.
+var signatureConfig = defaultSignatureConfig
+
+// Below minSignatureSize pixels the signature can't be read, small avatars
+// get it at that size. Scale is capped at maxSignatureScale, the gap to the
+// edge is a fraction of the avatar too.
+const (
+	minSignatureSize  = 8.0
+	maxSignatureScale = 0.2
+	signatureMargin   = 0.02
+)
+
+// LoadSignatureConfig reads the signature from the environment, where
+// operators can change or disable it per deployment:
+//
+//	SIGNATURE_DISABLED=1    no signature at all
+//	SIGNATURE_TEXT          the text, "@VacatoBot" by default
+//	SIGNATURE_IMAGE         an asset drawn instead of the text, e.g. logo.png
+//	SIGNATURE_CORNER        top-left, top-right, bottom-left or bottom-right
+//	SIGNATURE_SCALE         height as a fraction of the avatar, 0.035 by default
+//	SIGNATURE_OPACITY       from 0 to 1
+//
+// Unset variables keep their defaults. Images are looked up in the assets, so
+// the override directory has to be in place first.
+func LoadSignatureConfig(getenv func(string) string) (SignatureConfig, error) {
+	config := defaultSignatureConfig
+	config.Disabled = getenv("SIGNATURE_DISABLED") == "1"
+
+	if text := getenv("SIGNATURE_TEXT"); text != "" {
+		config.Text = text
+	}
+
+	if path := getenv("SIGNATURE_IMAGE"); path != "" {
+		if _, err := LoadAssetImage(path); err != nil {
+			return SignatureConfig{}, fmt.Errorf("SIGNATURE_IMAGE: %v", err)
+		}
+		config.Image = path
+	}
+
+	if corner := getenv("SIGNATURE_CORNER"); corner != "" {
+		if !hasKey(imageCorners, corner) {
+			return SignatureConfig{}, fmt.Errorf("SIGNATURE_CORNER: unknown corner %q", corner)
+		}
+		config.Corner = corner
+	}
+
+	if value := getenv("SIGNATURE_SCALE"); value != "" {
+		scale, err := strconv.ParseFloat(value, 64)
+		if err != nil || scale <= 0 || scale > maxSignatureScale {
+			return SignatureConfig{}, fmt.Errorf("SIGNATURE_SCALE must be above 0 and at most %g, got %q", maxSignatureScale, value)
+		}
+		config.Scale = scale
+	}
+
+	if value := getenv("SIGNATURE_OPACITY"); value != "" {
+		opacity, err := strconv.ParseFloat(value, 64)
+		if err != nil || opacity < 0 || opacity > 1 {
+			return SignatureConfig{}, fmt.Errorf("SIGNATURE_OPACITY must be between 0 and 1, got %q", value)
+		}
+		config.Opacity = opacity
+	}
+
+	return config, nil
+}
+
+// DrawSignature puts the deployment's signature in its corner, or centered
+// at the bottom or top of the circle for circular output so it isn't cut off.
+func DrawSignature(img *image.NRGBA, shape OutputShape) error {
+	return drawSignature(img, shape, signatureConfig)
+}
+
+func drawSignature(img *image.NRGBA, shape OutputShape, config SignatureConfig) error {
+	if config.Disabled || config.Opacity == 0 {
+		return nil
+	}
+
+	bounds := img.Bounds()
+	size := max(minSignatureSize, config.Scale*float64(min(bounds.Dx(), bounds.Dy())))
+	corner := imageCorners[config.Corner]
+	alpha := uint8(math.Round(config.Opacity * 255))
+
+	if config.Image != "" {
+		return drawSignatureImage(img, config.Image, size, corner, shape, alpha)
+	}
+	return drawSignatureText(img, config.Text, size, corner, shape, alpha)
+}
+
+func drawSignatureText(img *image.NRGBA, text string, size float64, corner image.Point, shape OutputShape, alpha uint8) error {
+	ttfFont, err := CachedLoadDefaultFont()
+	if err != nil {
+		return err
+	}
+
+	face, err := ttfFont.NewFace(size)
+	if err != nil {
+		return err
+	}
+	defer face.Close()
+
+	metrics := face.Metrics()
+	ascent, descent := float64(metrics.Ascent)/64, float64(metrics.Descent)/64
+	width := measureLineWidth(&font.Drawer{Face: face}, text)
+
+	x, y := signaturePosition(img.Bounds(), width, ascent+descent, corner, shape)
+	dot := fixed.Point26_6{
+		X: fixed.Int26_6(x * 64),
+		Y: fixed.Int26_6((y + ascent) * 64),
+	}
+
+	mask := image.NewAlpha(img.Bounds())
+	drawStringMask(mask, face, dot, text)
+	FillMask(img, mask, withAlpha(textColor, alpha))
+
+	return nil
+}
+
+func drawSignatureImage(img *image.NRGBA, path string, height float64, corner image.Point, shape OutputShape, alpha uint8) error {
+	source, err := LoadAssetImage(path)
+	if err != nil {
+		return err
+	}
+
+	sourceBounds := source.Bounds()
+	width := height * float64(sourceBounds.Dx()) / float64(sourceBounds.Dy())
+	x, y := signaturePosition(img.Bounds(), width, height, corner, shape)
+
+	rect := image.Rect(
+		int(math.Round(x)), int(math.Round(y)),
+		int(math.Round(x+width)), int(math.Round(y+height)),
+	)
+	scaled := image.NewNRGBA(rect)
+	xdraw.BiLinear.Scale(scaled, rect, source, sourceBounds, draw.Src, nil)
+
+	mask := image.NewAlpha(rect)
+	draw.Draw(mask, rect, image.NewUniform(color.Alpha{A: alpha}), image.Point{}, draw.Src)
+	FillMaskImage(img, mask, scaled)
+
+	return nil
+}
+
+// signaturePosition returns the top-left corner of a signature of the given
+// size in corner of bounds. For circular output it is centered instead, as
+// low, or as high for the top corners, as its outer edge stays inside the
+// circle and clear of the ring.
+func signaturePosition(bounds image.Rectangle, width, height float64, corner image.Point, shape OutputShape) (float64, float64) {
+	margin := signatureMargin * float64(min(bounds.Dx(), bounds.Dy()))
+
+	if shape.Circle {
+		centerX, centerY, radius := inscribedCircle(bounds)
+		usableRadius := radius*(1-2*shape.RingWidth) - margin
+
+		distance := 0.0
+		if usableRadius > width/2 {
+			distance = math.Sqrt(usableRadius*usableRadius - width*width/4)
+		}
+		if corner.Y < 0 {
+			return centerX - width/2, centerY - distance
+		}
+		return centerX - width/2, centerY + distance - height
+	}
+
+	x := float64(bounds.Min.X) + margin
+	if corner.X > 0 {
+		x = float64(bounds.Max.X) - margin - width
+	}
+	y := float64(bounds.Min.Y) + margin
+	if corner.Y > 0 {
+		y = float64(bounds.Max.Y) - margin - height
+	}
+	return x, y
+}
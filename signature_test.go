@@ -0,0 +1,179 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"testing"
+)
+
+func TestLoadSignatureConfig(t *testing.T) {
+	originalAssets := assets
+	assets = overlayFS{os.DirFS("./test_assets"), originalAssets}
+	defer func() { assets = originalAssets }()
+
+	withDefaults := func(change func(config *SignatureConfig)) SignatureConfig {
+		config := defaultSignatureConfig
+		change(&config)
+		return config
+	}
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected SignatureConfig
+		err      string
+	}{
+		{name: "Defaults", env: map[string]string{}, expected: defaultSignatureConfig},
+		{
+			name:     "Disabled",
+			env:      map[string]string{"SIGNATURE_DISABLED": "1"},
+			expected: withDefaults(func(config *SignatureConfig) { config.Disabled = true }),
+		},
+		{
+			name: "Custom text",
+			env:  map[string]string{"SIGNATURE_TEXT": "@AcmeBot", "SIGNATURE_CORNER": "top-left", "SIGNATURE_SCALE": "0.05", "SIGNATURE_OPACITY": "0.6"},
+			expected: withDefaults(func(config *SignatureConfig) {
+				config.Text, config.Corner, config.Scale, config.Opacity = "@AcmeBot", "top-left", 0.05, 0.6
+			}),
+		},
+		{
+			name:     "Logo",
+			env:      map[string]string{"SIGNATURE_IMAGE": "emoji/1f3d6.png"},
+			expected: withDefaults(func(config *SignatureConfig) { config.Image = "emoji/1f3d6.png" }),
+		},
+		{name: "Missing logo", env: map[string]string{"SIGNATURE_IMAGE": "logo.png"}, err: "SIGNATURE_IMAGE: "},
+		{name: "Unknown corner", env: map[string]string{"SIGNATURE_CORNER": "middle"}, err: `SIGNATURE_CORNER: unknown corner "middle"`},
+		{name: "Scale too large", env: map[string]string{"SIGNATURE_SCALE": "0.5"}, err: `SIGNATURE_SCALE must be above 0 and at most 0.2, got "0.5"`},
+		{name: "Scale not a number", env: map[string]string{"SIGNATURE_SCALE": "big"}, err: `SIGNATURE_SCALE must be above 0 and at most 0.2, got "big"`},
+		{name: "Opacity out of range", env: map[string]string{"SIGNATURE_OPACITY": "2"}, err: `SIGNATURE_OPACITY must be between 0 and 1, got "2"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := LoadSignatureConfig(func(key string) string { return tt.env[key] })
+			if tt.err != "" {
+				if err == nil || len(err.Error()) < len(tt.err) || err.Error()[:len(tt.err)] != tt.err {
+					t.Fatalf("Expected error starting with %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadSignatureConfig failed: %v", err)
+			}
+			if config != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, config)
+			}
+		})
+	}
+}
+
+func TestDrawSignatureScalesWithImage(t *testing.T) {
+	var ratio float64
+	for _, size := range []int{160, 320, 640, 1280} {
+		img := solidImage(size, black)
+		if err := drawSignature(img, OutputShape{}, defaultSignatureConfig); err != nil {
+			t.Fatalf("DrawSignature failed: %v", err)
+		}
+
+		drawn := drawnBounds(img, black)
+		margin := int(signatureMargin * float64(size))
+		if drawn.Empty() || drawn.Min.X < size/2 || drawn.Max.X > size-margin+1 || drawn.Max.Y > size-margin+1 {
+			t.Fatalf("Expected the signature in the bottom right corner of %dpx, got %v", size, drawn)
+		}
+
+		// Small avatars get the signature at its minimum size instead.
+		if defaultSignatureConfig.Scale*float64(size) < minSignatureSize {
+			continue
+		}
+
+		actual := float64(drawn.Dx()) / float64(size)
+		if ratio == 0 {
+			ratio = actual
+		} else if actual < ratio*0.9 || actual > ratio*1.1 {
+			t.Errorf("Expected the signature to keep its share of %.3f at %dpx, got %.3f", ratio, size, actual)
+		}
+	}
+}
+
+func TestDrawSignatureConfig(t *testing.T) {
+	originalAssets := assets
+	assets = overlayFS{os.DirFS("./test_assets"), originalAssets}
+	defer func() { assets = originalAssets }()
+
+	const size = 400
+	config := func(change func(config *SignatureConfig)) SignatureConfig {
+		config := defaultSignatureConfig
+		change(&config)
+		return config
+	}
+
+	tests := []struct {
+		name   string
+		config SignatureConfig
+		// The quarter of the avatar the signature must be in, nothing is
+		// drawn when empty.
+		quarter image.Rectangle
+		// The lightest pixel of the white text over black.
+		brightest uint8
+	}{
+		{name: "Default", config: defaultSignatureConfig, quarter: image.Rect(200, 200, 400, 400), brightest: 255},
+		{name: "Top left", config: config(func(c *SignatureConfig) { c.Corner = "top-left" }), quarter: image.Rect(0, 0, 200, 200), brightest: 255},
+		{name: "Half opacity", config: config(func(c *SignatureConfig) { c.Opacity = 0.5 }), quarter: image.Rect(200, 200, 400, 400), brightest: 128},
+		{name: "Disabled", config: config(func(c *SignatureConfig) { c.Disabled = true })},
+		{name: "Logo", config: config(func(c *SignatureConfig) { c.Image, c.Corner = "emoji/1f3d6.png", "bottom-left" }), quarter: image.Rect(0, 200, 200, 400)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := solidImage(size, black)
+			if err := drawSignature(img, OutputShape{}, tt.config); err != nil {
+				t.Fatalf("DrawSignature failed: %v", err)
+			}
+
+			drawn := drawnBounds(img, black)
+			if tt.quarter.Empty() {
+				if !drawn.Empty() {
+					t.Errorf("Expected nothing drawn, got %v", drawn)
+				}
+				return
+			}
+			if drawn.Empty() || !drawn.In(tt.quarter) {
+				t.Fatalf("Expected the signature in %v, got %v", tt.quarter, drawn)
+			}
+
+			if tt.config.Image != "" {
+				expected := int(tt.config.Scale * size)
+				if drawn.Dy() > expected || drawn.Dy() < expected-2 {
+					t.Errorf("Expected the logo %d pixels high, got %d", expected, drawn.Dy())
+				}
+				return
+			}
+
+			brightest := uint8(0)
+			for y := drawn.Min.Y; y < drawn.Max.Y; y++ {
+				for x := drawn.Min.X; x < drawn.Max.X; x++ {
+					brightest = max(brightest, img.NRGBAAt(x, y).R)
+				}
+			}
+			if brightest != tt.brightest {
+				t.Errorf("Expected the brightest pixel at %d, got %d", tt.brightest, brightest)
+			}
+		})
+	}
+}
+
+// drawnBounds is the smallest rectangle holding every pixel of img that
+// isn't background.
+func drawnBounds(img *image.NRGBA, background color.NRGBA) image.Rectangle {
+	var drawn image.Rectangle
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if img.NRGBAAt(x, y) != background {
+				drawn = drawn.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return drawn
+}
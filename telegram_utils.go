@@ -67,3 +67,20 @@ func GetUserAvatar(bot *tgbotapi.BotAPI, userId int64) (*image.NRGBA, error) {
 
 	return ImageToNRGBA(img), nil
 }
+
+// NewImageMessage sends a PNG as a photo, or as a document when img has
+// transparent pixels: Telegram recompresses photos to JPEG, which would fill
+// the corners of round avatars.
+func NewImageMessage(chatID int64, img *image.NRGBA, file tgbotapi.FileBytes, caption string, replyMarkup interface{}) tgbotapi.Chattable {
+	if !img.Opaque() {
+		document := tgbotapi.NewDocument(chatID, file)
+		document.Caption = caption
+		document.ReplyMarkup = replyMarkup
+		return document
+	}
+
+	photo := tgbotapi.NewPhoto(chatID, file)
+	photo.Caption = caption
+	photo.ReplyMarkup = replyMarkup
+	return photo
+}
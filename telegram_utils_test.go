@@ -0,0 +1,48 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestNewImageMessage(t *testing.T) {
+	square := solidImage(100, color.NRGBA{R: 200, G: 100, B: 50, A: 255})
+	round := solidImage(100, color.NRGBA{R: 200, G: 100, B: 50, A: 255})
+	ApplyOutputShape(round, outputShapes["circle"])
+
+	tests := []struct {
+		name     string
+		img      *image.NRGBA
+		document bool
+	}{
+		{name: "Opaque image is sent as a photo", img: square},
+		{name: "Round image is sent as a document", img: round, document: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := tgbotapi.FileBytes{Name: "avatar.png"}
+			switch message := NewImageMessage(1, tt.img, file, "caption", nil).(type) {
+			case tgbotapi.DocumentConfig:
+				if !tt.document {
+					t.Errorf("Expected a photo, got a document")
+				}
+				if message.Caption != "caption" {
+					t.Errorf("Expected the caption to be kept, got %q", message.Caption)
+				}
+			case tgbotapi.PhotoConfig:
+				if tt.document {
+					t.Errorf("Expected a document, got a photo")
+				}
+				if message.Caption != "caption" {
+					t.Errorf("Expected the caption to be kept, got %q", message.Caption)
+				}
+			default:
+				t.Fatalf("Unexpected message %T", message)
+			}
+		})
+	}
+}
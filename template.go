@@ -0,0 +1,501 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strings"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// Template is a look users can pick: an ordered list of layers painted onto
+// the avatar from the bottom up. Templates are JSON files in templateDir of
+// the assets, so new looks can be added by dropping a file into the override
+// directory.
+type Template struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Layers      []Layer `json:"layers"`
+}
+
+const (
+	LayerGradient  = "gradient"
+	LayerImage     = "image"
+	LayerText      = "text"
+	LayerBadge     = "badge"
+	LayerMask      = "mask"
+	LayerSignature = "signature"
+	LayerRibbon    = "ribbon"
+)
+
+// Layer is one step of a template. Fields naming a preset fall back to the
+// user's own choice when left empty. Area is the part of the avatar covered
+// by the layer, in fractions of its size, and defaults to all of it.
+type Layer struct {
+	Type     string   `json:"type"`
+	Area     *Area    `json:"area,omitempty"`
+	Opacity  *float64 `json:"opacity,omitempty"`
+	Shape    string   `json:"shape,omitempty"`
+	Palette  string   `json:"palette,omitempty"`
+	Space    string   `json:"space,omitempty"`
+	Blend    string   `json:"blend,omitempty"`
+	Image    string   `json:"image,omitempty"`
+	Style    string   `json:"style,omitempty"`
+	Font     string   `json:"font,omitempty"`
+	MaxLines int      `json:"max_lines,omitempty"`
+	Position string   `json:"position,omitempty"`
+	Badge    string   `json:"badge,omitempty"`
+	Output   string   `json:"output,omitempty"`
+	Corner   string   `json:"corner,omitempty"`
+	Label    string   `json:"label,omitempty"`
+}
+
+type Area struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+func (a *Area) rect(bounds image.Rectangle) image.Rectangle {
+	if a == nil {
+		return bounds
+	}
+
+	w, h := float64(bounds.Dx()), float64(bounds.Dy())
+	return image.Rect(
+		bounds.Min.X+int(a.X*w), bounds.Min.Y+int(a.Y*h),
+		bounds.Min.X+int((a.X+a.Width)*w), bounds.Min.Y+int((a.Y+a.Height)*h),
+	)
+}
+
+// layerFields lists the fields each layer type understands. Setting any
+// other field is an error, so typos and misplaced options don't go unnoticed.
+var layerFields = map[string][]string{
+	LayerGradient:  {"opacity", "shape", "palette", "space", "blend"},
+	LayerImage:     {"area", "opacity", "image"},
+	LayerText:      {"area", "style", "font", "max_lines", "position"},
+	LayerBadge:     {"badge"},
+	LayerMask:      {"output"},
+	LayerSignature: {},
+	LayerRibbon:    {"palette", "style", "font", "corner", "label"},
+}
+
+func (l Layer) setFields() []string {
+	var fields []string
+	add := func(name string, set bool) {
+		if set {
+			fields = append(fields, name)
+		}
+	}
+
+	add("area", l.Area != nil)
+	add("opacity", l.Opacity != nil)
+	add("shape", l.Shape != "")
+	add("palette", l.Palette != "")
+	add("space", l.Space != "")
+	add("blend", l.Blend != "")
+	add("image", l.Image != "")
+	add("style", l.Style != "")
+	add("font", l.Font != "")
+	add("max_lines", l.MaxLines != 0)
+	add("position", l.Position != "")
+	add("badge", l.Badge != "")
+	add("output", l.Output != "")
+	add("corner", l.Corner != "")
+	add("label", l.Label != "")
+	return fields
+}
+
+const defaultLayerOpacity = 0.5
+
+const templateDir = "templates"
+
+const defaultTemplate = "classic"
+
+var (
+	renderTemplates     = map[string]Template{}
+	renderTemplateNames []string
+)
+
+var templateNamePattern = regexp.MustCompile(`^[a-z0-9-]{1,32}$`)
+
+// LoadTemplates reads and validates every template in the assets. The
+// default template is listed first, the others in file name order. Templates
+// name badges, so those are loaded first.
+func LoadTemplates() error {
+	if err := LoadRingBadges(); err != nil {
+		return err
+	}
+
+	templates := map[string]Template{}
+	var names []string
+
+	for _, file := range assetFiles(templateDir, "*.json") {
+		tmpl, err := loadTemplate(file)
+		if err != nil {
+			return err
+		}
+		if _, exists := templates[tmpl.Name]; exists {
+			return fmt.Errorf("%s: template %q is already defined", file, tmpl.Name)
+		}
+
+		templates[tmpl.Name] = tmpl
+		names = append(names, tmpl.Name)
+	}
+
+	index := slices.Index(names, defaultTemplate)
+	if index == -1 {
+		return fmt.Errorf("the %s template is missing from %s", defaultTemplate, templateDir)
+	}
+	names = append([]string{defaultTemplate}, slices.Delete(names, index, index+1)...)
+
+	renderTemplates = templates
+	renderTemplateNames = names
+	return nil
+}
+
+func loadTemplate(file string) (Template, error) {
+	data, err := fs.ReadFile(assets, file)
+	if err != nil {
+		return Template{}, fmt.Errorf("error reading template: %v", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var tmpl Template
+	if err := decoder.Decode(&tmpl); err != nil {
+		return Template{}, fmt.Errorf("%s: error parsing template: %v", file, err)
+	}
+	if err := tmpl.Validate(); err != nil {
+		return Template{}, fmt.Errorf("%s: %v", file, err)
+	}
+	return tmpl, nil
+}
+
+func (t Template) Validate() error {
+	if !templateNamePattern.MatchString(t.Name) {
+		return fmt.Errorf("template name %q must be 1 to 32 lowercase letters, digits or dashes", t.Name)
+	}
+	if len(t.Layers) == 0 {
+		return fmt.Errorf("template %s has no layers", t.Name)
+	}
+
+	for i, layer := range t.Layers {
+		if err := layer.Validate(); err != nil {
+			return fmt.Errorf("layer %d (%s): %v", i+1, layer.Type, err)
+		}
+	}
+	return nil
+}
+
+func (l Layer) Validate() error {
+	allowed, ok := layerFields[l.Type]
+	if !ok {
+		return fmt.Errorf("unknown layer type %q", l.Type)
+	}
+	for _, field := range l.setFields() {
+		if !slices.Contains(allowed, field) {
+			return fmt.Errorf("%s doesn't apply to %s layers", field, l.Type)
+		}
+	}
+
+	if l.Area != nil {
+		a := l.Area
+		if a.X < 0 || a.Y < 0 || a.Width <= 0 || a.Height <= 0 || a.X+a.Width > 1 || a.Y+a.Height > 1 {
+			return fmt.Errorf("area must lie within the avatar, with sizes and offsets between 0 and 1")
+		}
+	}
+	if l.Opacity != nil && (*l.Opacity < 0 || *l.Opacity > 1) {
+		return fmt.Errorf("opacity must be between 0 and 1, got %g", *l.Opacity)
+	}
+	if l.MaxLines < 0 {
+		return fmt.Errorf("max_lines must be positive, got %d", l.MaxLines)
+	}
+	if l.Area != nil && l.Position != "" {
+		return fmt.Errorf("area and position can't be used together")
+	}
+
+	presets := []struct {
+		field, value string
+		exists       bool
+	}{
+		{"shape", l.Shape, hasKey(gradientShapes, l.Shape)},
+		{"palette", l.Palette, hasKey(gradientPalettes, l.Palette) || l.Palette == autoGradientPalette},
+		{"space", l.Space, hasKey(colorSpaces, l.Space)},
+		{"blend", l.Blend, hasKey(blendModes, l.Blend)},
+		{"style", l.Style, hasKey(textStyles, l.Style)},
+		{"font", l.Font, hasKey(fontFamilies, l.Font)},
+		{"position", l.Position, hasKey(textPositions, l.Position)},
+		{"badge", l.Badge, hasKey(ringBadges, l.Badge)},
+		{"output", l.Output, hasKey(outputShapes, l.Output)},
+		{"corner", l.Corner, hasKey(imageCorners, l.Corner)},
+	}
+	for _, preset := range presets {
+		if preset.value != "" && !preset.exists {
+			return fmt.Errorf("unknown %s %q", preset.field, preset.value)
+		}
+	}
+
+	if l.Type == LayerImage {
+		if l.Image == "" {
+			return fmt.Errorf("image layers need an image")
+		}
+		if _, err := LoadAssetImage(l.Image); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func hasKey[V any](m map[string]V, key string) bool {
+	_, ok := m[key]
+	return ok
+}
+
+// RenderTemplate paints the template layers onto img. Layers without their
+// own presets use the user's settings, so the default template looks the way
+// the user set things up. The returned layout is the one of the last text
+// layer. Text covering the whole avatar is moved off the faces in it.
+func RenderTemplate(img *image.NRGBA, tmpl Template, text string, settings UserSettings) (TextLayout, error) {
+	faces, err := templateFaces(img, tmpl)
+	if err != nil {
+		return TextLayout{}, err
+	}
+	return renderTemplateFrame(img, tmpl, text, settings, faces, animationFrame{})
+}
+
+// templateFaces detects the faces in img when a text layer of tmpl is free
+// to move away from them.
+func templateFaces(img *image.NRGBA, tmpl Template) ([]image.Rectangle, error) {
+	for _, layer := range tmpl.Layers {
+		if layer.Type == LayerText && layer.Area == nil {
+			return DetectFaces(img)
+		}
+	}
+	return nil, nil
+}
+
+// renderTemplateFrame is RenderTemplate with the faces already detected and
+// the changes of one animation frame applied.
+func renderTemplateFrame(img *image.NRGBA, tmpl Template, text string, settings UserSettings, faces []image.Rectangle, frame animationFrame) (TextLayout, error) {
+	outputShape := settings.OutputShape
+	for _, layer := range tmpl.Layers {
+		if layer.Type == LayerMask && layer.Output != "" {
+			outputShape = outputShapes[layer.Output]
+		}
+	}
+	if frame.RingPulse != 0 {
+		if outputShape.RingWidth == 0 || outputShape.RingColor.A == 0 {
+			outputShape = outputShapes["ring"]
+		}
+		outputShape.RingWidth *= 1 + frame.RingPulse
+	}
+
+	var layout TextLayout
+	for i, layer := range tmpl.Layers {
+		var err error
+
+		switch layer.Type {
+		case LayerGradient:
+			drawGradientLayer(img, layer, settings, frame.HueShift)
+
+		case LayerImage:
+			err = drawImageLayer(img, layer)
+
+		case LayerText:
+			layout, err = drawTextLayer(img, layer, text, settings, outputShape, faces, frame.Reveal)
+
+		case LayerBadge:
+			badge := settings.RingBadge
+			if layer.Badge != "" {
+				badge = ringBadges[layer.Badge]
+			}
+			err = DrawRingBadge(img, badge)
+
+		case LayerSignature:
+			err = DrawSignature(img, outputShape)
+
+		case LayerRibbon:
+			err = drawRibbonLayer(img, layer, text, settings, outputShape, frame.HueShift)
+
+		case LayerMask:
+			ApplyOutputShape(img, outputShape)
+		}
+
+		if err != nil {
+			return TextLayout{}, fmt.Errorf("error drawing layer %d (%s): %v", i+1, layer.Type, err)
+		}
+	}
+
+	return layout, nil
+}
+
+func layerOpacity(layer Layer) float64 {
+	if layer.Opacity == nil {
+		return defaultLayerOpacity
+	}
+	return *layer.Opacity
+}
+
+func drawGradientLayer(img *image.NRGBA, layer Layer, settings UserSettings, hueShift float64) {
+	if layer.Palette != "" {
+		settings.Palette = layer.Palette
+	}
+	if layer.Space != "" {
+		settings.ColorSpace = colorSpaces[layer.Space]
+	}
+	spec := settings.GradientSpec()
+	if settings.Palette == autoGradientPalette {
+		spec.Stops = AvatarPalette(img)
+	}
+	if layer.Shape != "" {
+		spec.Shape = gradientShapes[layer.Shape]
+	}
+	if hueShift != 0 {
+		spec.Stops = rotateStops(spec.Stops, hueShift)
+	}
+
+	blendMode := settings.BlendMode
+	if layer.Blend != "" {
+		blendMode = blendModes[layer.Blend]
+	}
+
+	// Gradients from the avatar colors and hue-shifted animation frames are
+	// not cached, they are rarely the same twice.
+	var gradient *image.NRGBA
+	if settings.Palette == autoGradientPalette || hueShift != 0 {
+		gradient = CreateGradient(img.Bounds().Dx(), img.Bounds().Dy(), spec)
+	} else {
+		gradient = CachedCreateGradient(img.Bounds().Dx(), img.Bounds().Dy(), spec)
+	}
+	OverlayImage(img, gradient, layerOpacity(layer), blendMode, settings.ColorSpace)
+}
+
+func rotateStops(stops []GradientStop, degrees float64) []GradientStop {
+	rotated := make([]GradientStop, len(stops))
+	for i, stop := range stops {
+		rotated[i] = GradientStop{Position: stop.Position, Color: RotateHue(stop.Color, degrees)}
+	}
+	return rotated
+}
+
+func drawImageLayer(img *image.NRGBA, layer Layer) error {
+	source, err := LoadAssetImage(layer.Image)
+	if err != nil {
+		return err
+	}
+
+	rect := layer.Area.rect(img.Bounds())
+	scaled := image.NewNRGBA(rect)
+	xdraw.BiLinear.Scale(scaled, rect, source, source.Bounds(), draw.Src, nil)
+
+	coverage := image.NewUniform(color.Alpha{A: uint8(layerOpacity(layer)*255 + 0.5)})
+	mask := image.NewAlpha(rect)
+	draw.Draw(mask, rect, coverage, image.Point{}, draw.Src)
+	FillMaskImage(img, mask, scaled)
+
+	return nil
+}
+
+// drawRibbonLayer puts the ribbon across the layer corner, holding the layer
+// label or else the user's text. It follows the hue of animated gradients.
+func drawRibbonLayer(img *image.NRGBA, layer Layer, text string, settings UserSettings, outputShape OutputShape, hueShift float64) error {
+	style, fontFamily, corner := settings.TextStyle, settings.FontFamily, defaultRibbonCorner
+	if layer.Style != "" {
+		style = textStyles[layer.Style]
+	}
+	if layer.Font != "" {
+		fontFamily = layer.Font
+	}
+	if layer.Corner != "" {
+		corner = layer.Corner
+	}
+	if layer.Label != "" {
+		text = layer.Label
+	}
+
+	if layer.Palette != "" {
+		settings.Palette = layer.Palette
+	}
+	spec := settings.GradientSpec()
+	if settings.Palette == autoGradientPalette {
+		spec.Stops = AvatarPalette(img)
+	}
+	spec.Shape = LinearGradient(90)
+	if hueShift != 0 {
+		spec.Stops = rotateStops(spec.Stops, hueShift)
+	}
+
+	return DrawRibbon(img, imageCorners[corner], text, fontFamily, style, spec, outputShape.Circle)
+}
+
+// drawTextLayer fits text in the layer area, or where the text position
+// puts it. Text covering the whole avatar goes next to the faces when the
+// position allows and there is room, and keeps inside the circle when the
+// output is round.
+func drawTextLayer(img *image.NRGBA, layer Layer, text string, settings UserSettings, outputShape OutputShape, faces []image.Rectangle, reveal int) (TextLayout, error) {
+	style, fontFamily, position := settings.TextStyle, settings.FontFamily, settings.TextPosition
+	if layer.Style != "" {
+		style = textStyles[layer.Style]
+	}
+	if layer.Font != "" {
+		fontFamily = layer.Font
+	}
+	if layer.Position != "" {
+		position = textPositions[layer.Position]
+	}
+
+	options := defaultTextLayoutOptions
+	if layer.MaxLines > 0 {
+		options.MaxLines = layer.MaxLines
+	}
+	options.Reveal = reveal
+
+	bounds := img.Bounds()
+	region, avoidsFaces := faceFreeRegion(bounds, faces, outputShape.Circle)
+
+	var rect image.Rectangle
+	switch {
+	case layer.Area != nil:
+		rect = layer.Area.rect(bounds)
+
+	case position.Area != nil:
+		rect = positionRect(bounds, position, outputShape.Circle)
+
+	case position.AvoidFaces && avoidsFaces:
+		rect = region
+
+	default:
+		rect = bounds
+		options.Circle = outputShape.Circle
+	}
+
+	if layer.Area == nil && position.Banner != BannerNone {
+		if err := drawBanner(img, rect, text, fontFamily, options, position.Banner, settings); err != nil {
+			return TextLayout{}, err
+		}
+	}
+
+	area := img.SubImage(rect).(*image.NRGBA)
+	return DrawTextToImage(area, text, fontFamily, style, options)
+}
+
+// templateMenuText lists the templates with their descriptions, the menu
+// buttons only have room for names.
+func templateMenuText() string {
+	var lines []string
+	lines = append(lines, "Pick a look for your next avatar:")
+	for _, name := range renderTemplateNames {
+		lines = append(lines, "• "+choiceLabel(name)+": "+renderTemplates[name].Description)
+	}
+	return strings.Join(lines, "\n")
+}
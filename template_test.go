@@ -0,0 +1,281 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadTemplates(t *testing.T) {
+	if err := LoadTemplates(); err != nil {
+		t.Fatalf("LoadTemplates failed: %v", err)
+	}
+
+	if renderTemplateNames[0] != defaultTemplate {
+		t.Errorf("Expected %s to be listed first, got %v", defaultTemplate, renderTemplateNames)
+	}
+	for _, name := range []string{"classic", "spotlight", "open-to-work", "out-of-office"} {
+		if !slices.Contains(renderTemplateNames, name) {
+			t.Errorf("Expected the %s template to be loaded", name)
+		}
+	}
+}
+
+func TestLoadTemplatesFromOverride(t *testing.T) {
+	originalAssets := assets
+	defer func() {
+		assets = originalAssets
+		LoadTemplates()
+	}()
+
+	tests := []struct {
+		name     string
+		files    map[string]string
+		expected string
+	}{
+		{
+			name:  "Added template",
+			files: map[string]string{"templates/plain.json": `{"name": "plain", "layers": [{"type": "text"}]}`},
+		},
+		{
+			name:     "Duplicate name",
+			files:    map[string]string{"templates/copy.json": `{"name": "classic", "layers": [{"type": "text"}]}`},
+			expected: `templates/copy.json: template "classic" is already defined`,
+		},
+		{
+			name:     "Unknown field",
+			files:    map[string]string{"templates/typo.json": `{"name": "typo", "layer": []}`},
+			expected: `templates/typo.json: error parsing template: json: unknown field "layer"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			override := fstest.MapFS{}
+			for name, data := range tt.files {
+				override[name] = &fstest.MapFile{Data: []byte(data)}
+			}
+			assets = overlayFS{override, originalAssets}
+
+			err := LoadTemplates()
+			if tt.expected == "" {
+				if err != nil {
+					t.Fatalf("LoadTemplates failed: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.expected {
+				t.Errorf("Expected error %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestTemplateValidate(t *testing.T) {
+	opacity := 1.5
+
+	tests := []struct {
+		name     string
+		template Template
+		expected string
+	}{
+		{
+			name:     "Bad name",
+			template: Template{Name: "My Look", Layers: []Layer{{Type: LayerText}}},
+			expected: "template name",
+		},
+		{
+			name:     "No layers",
+			template: Template{Name: "empty"},
+			expected: "template empty has no layers",
+		},
+		{
+			name:     "Unknown layer type",
+			template: Template{Name: "look", Layers: []Layer{{Type: "sparkles"}}},
+			expected: `layer 1 (sparkles): unknown layer type "sparkles"`,
+		},
+		{
+			name:     "Misplaced field",
+			template: Template{Name: "look", Layers: []Layer{{Type: LayerText}, {Type: LayerGradient, Style: "outline"}}},
+			expected: "layer 2 (gradient): style doesn't apply to gradient layers",
+		},
+		{
+			name:     "Unknown preset",
+			template: Template{Name: "look", Layers: []Layer{{Type: LayerGradient, Palette: "neon"}}},
+			expected: `layer 1 (gradient): unknown palette "neon"`,
+		},
+		{
+			name:     "Unknown color space",
+			template: Template{Name: "look", Layers: []Layer{{Type: LayerGradient, Space: "cmyk"}}},
+			expected: `layer 1 (gradient): unknown space "cmyk"`,
+		},
+		{
+			name:     "Color space on a text layer",
+			template: Template{Name: "look", Layers: []Layer{{Type: LayerText, Space: "srgb"}}},
+			expected: "layer 1 (text): space doesn't apply to text layers",
+		},
+		{
+			name:     "Opacity out of range",
+			template: Template{Name: "look", Layers: []Layer{{Type: LayerGradient, Opacity: &opacity}}},
+			expected: "layer 1 (gradient): opacity must be between 0 and 1, got 1.5",
+		},
+		{
+			name:     "Area outside the avatar",
+			template: Template{Name: "look", Layers: []Layer{{Type: LayerText, Area: &Area{X: 0.5, Y: 0, Width: 0.6, Height: 1}}}},
+			expected: "layer 1 (text): area must lie within the avatar",
+		},
+		{
+			name:     "Unknown position",
+			template: Template{Name: "look", Layers: []Layer{{Type: LayerText, Position: "middle"}}},
+			expected: `layer 1 (text): unknown position "middle"`,
+		},
+		{
+			name:     "Area and position together",
+			template: Template{Name: "look", Layers: []Layer{{Type: LayerText, Position: "top", Area: &Area{X: 0, Y: 0, Width: 1, Height: 0.5}}}},
+			expected: "layer 1 (text): area and position can't be used together",
+		},
+		{
+			name:     "Unknown ribbon corner",
+			template: Template{Name: "look", Layers: []Layer{{Type: LayerRibbon, Corner: "middle"}}},
+			expected: `layer 1 (ribbon): unknown corner "middle"`,
+		},
+		{
+			name:     "Label on a text layer",
+			template: Template{Name: "look", Layers: []Layer{{Type: LayerText, Label: "Away"}}},
+			expected: "layer 1 (text): label doesn't apply to text layers",
+		},
+		{
+			name:     "Image layer without an image",
+			template: Template{Name: "look", Layers: []Layer{{Type: LayerImage}}},
+			expected: "layer 1 (image): image layers need an image",
+		},
+		{
+			name:     "Missing image",
+			template: Template{Name: "look", Layers: []Layer{{Type: LayerImage, Image: "badges/missing.png"}}},
+			expected: "layer 1 (image): error opening image",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.template.Validate()
+			if err == nil || !strings.HasPrefix(err.Error(), tt.expected) {
+				t.Errorf("Expected error starting with %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestRenderClassicTemplateMatchesSettings(t *testing.T) {
+	if err := LoadTemplates(); err != nil {
+		t.Fatalf("LoadTemplates failed: %v", err)
+	}
+
+	settings := defaultUserSettings()
+	settings.OutputShape = outputShapes["ring"]
+	settings.RingBadge = ringBadges["vacation"]
+	background := color.NRGBA{R: 120, G: 90, B: 60, A: 255}
+
+	rendered := solidImage(200, background)
+	if _, err := RenderTemplate(rendered, renderTemplates["classic"], "Day off!", settings); err != nil {
+		t.Fatalf("RenderTemplate failed: %v", err)
+	}
+
+	expected := solidImage(200, background)
+	gradient := CachedCreateGradient(200, 200, settings.GradientSpec())
+	OverlayImage(expected, gradient, 0.5, settings.BlendMode, settings.ColorSpace)
+	options := defaultTextLayoutOptions
+	options.Circle = true
+	DrawTextToImage(expected, "Day off!", settings.FontFamily, settings.TextStyle, options)
+	DrawRingBadge(expected, settings.RingBadge)
+	DrawSignature(expected, settings.OutputShape)
+	ApplyOutputShape(expected, settings.OutputShape)
+
+	if !CompareImages(rendered, expected) {
+		t.Error("Expected the classic template to follow the user's settings")
+	}
+}
+
+func TestRenderTemplateGradientSpace(t *testing.T) {
+	background := color.NRGBA{R: 120, G: 90, B: 60, A: 255}
+	render := func(layer Layer, settings UserSettings) *image.NRGBA {
+		img := solidImage(100, background)
+		if _, err := RenderTemplate(img, Template{Name: "gradient", Layers: []Layer{layer}}, "", settings); err != nil {
+			t.Fatalf("RenderTemplate failed: %v", err)
+		}
+		return img
+	}
+
+	srgbSettings := defaultUserSettings()
+	srgbSettings.ColorSpace = colorSpaces["srgb"]
+
+	fromLayer := render(Layer{Type: LayerGradient, Space: "srgb"}, defaultUserSettings())
+	fromSettings := render(Layer{Type: LayerGradient}, srgbSettings)
+	oklab := render(Layer{Type: LayerGradient}, defaultUserSettings())
+
+	if !CompareImages(fromLayer, fromSettings) {
+		t.Error("Expected the layer space to be used like the user's own")
+	}
+	if CompareImages(fromLayer, oklab) {
+		t.Error("Expected sRGB and OKLab gradients to differ")
+	}
+}
+
+func TestDrawGradientLayerSkipsCacheForHueShift(t *testing.T) {
+	gradientCacheMu.Lock()
+	before := len(gradientCache)
+	gradientCacheMu.Unlock()
+
+	for _, hueShift := range []float64{15, 30, 45} {
+		drawGradientLayer(solidImage(100, color.NRGBA{R: 120, G: 90, B: 60, A: 255}), Layer{Type: LayerGradient}, defaultUserSettings(), hueShift)
+	}
+
+	gradientCacheMu.Lock()
+	defer gradientCacheMu.Unlock()
+	if len(gradientCache) != before {
+		t.Errorf("Expected hue-shifted gradients to stay out of the cache, it grew from %d to %d", before, len(gradientCache))
+	}
+}
+
+func TestRenderTemplateLayerAreas(t *testing.T) {
+	originalAssets := assets
+	assets = overlayFS{os.DirFS("./test_assets"), originalAssets}
+	defer func() { assets = originalAssets }()
+
+	opaque := 1.0
+	tmpl := Template{Name: "areas", Layers: []Layer{
+		{Type: LayerImage, Image: "emoji/1f3d6.png", Area: &Area{X: 0, Y: 0, Width: 0.5, Height: 0.5}, Opacity: &opaque},
+		{Type: LayerText, Area: &Area{X: 0, Y: 0.5, Width: 1, Height: 0.5}, Style: "plain"},
+	}}
+	if err := tmpl.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, 200, 200))
+	if _, err := RenderTemplate(img, tmpl, "Hello", defaultUserSettings()); err != nil {
+		t.Fatalf("RenderTemplate failed: %v", err)
+	}
+
+	if center := img.NRGBAAt(50, 50); center != (color.NRGBA{R: 255, G: 160, B: 0, A: 255}) {
+		t.Errorf("Expected the image in the top left quarter, got %v", center)
+	}
+	if corner := img.NRGBAAt(150, 50); corner.A != 0 {
+		t.Errorf("Expected the top right quarter to stay empty, got %v", corner)
+	}
+
+	textPixels := 0
+	for y := 100; y < 200; y++ {
+		for x := 0; x < 200; x++ {
+			if img.NRGBAAt(x, y) == textColor {
+				textPixels++
+			}
+		}
+	}
+	if textPixels == 0 {
+		t.Error("Expected text in the bottom half")
+	}
+}
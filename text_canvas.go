@@ -0,0 +1,106 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"slices"
+
+	"github.com/go-text/typesetting/di"
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// textCanvas collects a block of text before it is painted: glyph coverage
+// goes to a mask that gets the text style colors, color emoji go to their
+// own layer which is composited on top unchanged.
+type textCanvas struct {
+	glyphs   *image.Alpha
+	emoji    *image.NRGBA
+	hasEmoji bool
+}
+
+func newTextCanvas(bounds image.Rectangle) *textCanvas {
+	return &textCanvas{
+		glyphs: image.NewAlpha(bounds),
+		emoji:  image.NewNRGBA(bounds),
+	}
+}
+
+// drawLine draws text left to right from dot. In right-to-left lines the
+// emoji and text runs are laid out in reverse so they keep their reading order.
+func (c *textCanvas) drawLine(face font.Face, dot fixed.Point26_6, text string) {
+	runs := splitEmoji(text)
+	if baseDirection(text) == di.DirectionRTL {
+		slices.Reverse(runs)
+	}
+
+	for _, run := range runs {
+		var bitmap *image.NRGBA
+		if run.Emoji {
+			bitmap = CachedLoadEmoji(run.Text)
+		}
+
+		if shaped, ok := face.(*fallbackFace); ok && bitmap == nil && needsShaping(run.Text) {
+			line := shaped.collection.shapeLine(run.Text, shaped.size)
+			shaped.collection.drawShapedLine(c.glyphs, line, shaped.size, dot)
+			dot.X += line.advance
+			continue
+		}
+
+		if bitmap == nil {
+			drawer := &font.Drawer{
+				Dst:  c.glyphs,
+				Src:  image.Opaque,
+				Face: face,
+				Dot:  dot,
+			}
+			drawer.DrawString(run.Text)
+			dot = drawer.Dot
+			continue
+		}
+
+		c.drawEmoji(bitmap, emojiRect(face, dot))
+		dot.X += emojiAdvance(face)
+	}
+}
+
+func (c *textCanvas) drawEmoji(bitmap *image.NRGBA, rect image.Rectangle) {
+	xdraw.BiLinear.Scale(c.emoji, rect, bitmap, bitmap.Bounds(), draw.Over, nil)
+	c.hasEmoji = true
+}
+
+// silhouette is the coverage of glyphs and emoji together, the shape that
+// strokes and shadows are built from.
+func (c *textCanvas) silhouette() *image.Alpha {
+	if !c.hasEmoji {
+		return c.glyphs
+	}
+
+	bounds := c.glyphs.Bounds()
+	combined := image.NewAlpha(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			combined.SetAlpha(x, y, color.Alpha{A: max(c.glyphs.AlphaAt(x, y).A, c.emoji.NRGBAAt(x, y).A)})
+		}
+	}
+	return combined
+}
+
+// emojiScale is the emoji size relative to the line height of the face, so
+// emoji sit between the ascender and descender like glyphs do.
+const emojiScale = 0.9
+
+func emojiAdvance(face font.Face) fixed.Int26_6 {
+	metrics := face.Metrics()
+	return fixed.Int26_6(float64(metrics.Ascent+metrics.Descent) * emojiScale)
+}
+
+func emojiRect(face font.Face, dot fixed.Point26_6) image.Rectangle {
+	metrics := face.Metrics()
+	size := emojiAdvance(face)
+	top := dot.Y - metrics.Ascent + (metrics.Ascent+metrics.Descent-size)/2
+
+	return image.Rect(dot.X.Round(), top.Round(), (dot.X + size).Round(), (top + size).Round())
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"slices"
+)
+
+// textContrastRatio is the WCAG AA target for normal text. Avatars are mostly
+// seen as thumbnails, so text isn't treated as large.
+const textContrastRatio = 4.5
+
+// The background of a line is judged by its darkest and lightest parts, with
+// this share of the pixels at either end ignored as noise.
+const backgroundPercentile = 0.1
+
+var (
+	black = color.NRGBA{R: 0, G: 0, B: 0, A: 255}
+	white = color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+)
+
+// relativeLuminance is the WCAG relative luminance of c, ignoring its alpha.
+func relativeLuminance(c color.NRGBA) float64 {
+	return 0.2126*srgbToLinearTable[c.R] + 0.7152*srgbToLinearTable[c.G] + 0.0722*srgbToLinearTable[c.B]
+}
+
+// contrastRatio is the WCAG contrast ratio between two colors, from 1 for
+// equal luminance to 21 for black on white.
+func contrastRatio(a, b color.NRGBA) float64 {
+	la, lb := relativeLuminance(a), relativeLuminance(b)
+	return (max(la, lb) + 0.05) / (min(la, lb) + 0.05)
+}
+
+// contrastingColor is black or white, whichever stands out more against c.
+func contrastingColor(c color.NRGBA) color.NRGBA {
+	if contrastRatio(c, black) > contrastRatio(c, white) {
+		return black
+	}
+	return white
+}
+
+// lineBackground returns the darkest and lightest colors of the opaque
+// pixels of img in rect. ok is false when there are none to judge by.
+func lineBackground(img *image.NRGBA, rect image.Rectangle) (color.NRGBA, color.NRGBA, bool) {
+	rect = rect.Intersect(img.Bounds())
+
+	var pixels []color.NRGBA
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			if pixel := img.NRGBAAt(x, y); pixel.A >= 128 {
+				pixels = append(pixels, pixel)
+			}
+		}
+	}
+	if len(pixels) == 0 {
+		return color.NRGBA{}, color.NRGBA{}, false
+	}
+
+	slices.SortFunc(pixels, func(a, b color.NRGBA) int {
+		la, lb := relativeLuminance(a), relativeLuminance(b)
+		switch {
+		case la < lb:
+			return -1
+		case la > lb:
+			return 1
+		}
+		return 0
+	})
+
+	cut := int(float64(len(pixels)-1) * backgroundPercentile)
+	return pixels[cut], pixels[len(pixels)-1-cut], true
+}
+
+// contrastStyle adjusts style to the background of rect in img. The text
+// color is kept when it meets style.ContrastRatio against both the darkest
+// and the lightest part of the background, otherwise black or white is used.
+// When neither does, the returned plate color is the backing plate to put
+// behind the line, its alpha just enough to reach the ratio. Stroke and
+// shadow colors are flipped with the text so they keep setting it off.
+func contrastStyle(img *image.NRGBA, rect image.Rectangle, style TextStyle) (TextStyle, color.NRGBA) {
+	dark, light, ok := lineBackground(img, rect)
+	if style.ContrastRatio <= 0 || !ok {
+		return style, color.NRGBA{}
+	}
+
+	worstContrast := func(c color.NRGBA) float64 {
+		return min(contrastRatio(c, dark), contrastRatio(c, light))
+	}
+
+	textColor := style.Color
+	if worstContrast(textColor) < style.ContrastRatio {
+		for _, candidate := range []color.NRGBA{white, black} {
+			candidate.A = style.Color.A
+			if worstContrast(candidate) > worstContrast(textColor) {
+				textColor = candidate
+			}
+		}
+	}
+
+	var plate color.NRGBA
+	if worstContrast(textColor) < style.ContrastRatio {
+		plate = contrastingColor(textColor)
+		plate.A = plateOpacity(textColor, plate, dark, light, style.ContrastRatio)
+	}
+
+	if textColor != style.Color {
+		style.Color = textColor
+		if contrastRatio(style.StrokeColor, textColor) < style.ContrastRatio {
+			style.StrokeColor = withAlpha(contrastingColor(textColor), style.StrokeColor.A)
+		}
+		if contrastRatio(style.ShadowColor, textColor) < style.ContrastRatio {
+			style.ShadowColor = withAlpha(contrastingColor(textColor), style.ShadowColor.A)
+		}
+	}
+
+	return style, plate
+}
+
+// plateOpacity finds the lowest opacity of plate over both ends of the
+// background that gives text the target contrast.
+func plateOpacity(text, plate, dark, light color.NRGBA, target float64) uint8 {
+	meets := func(alpha uint8) bool {
+		coverage := uint32(alpha) * 0x101
+		for _, background := range []color.NRGBA{dark, light} {
+			if contrastRatio(text, CompositeOver(background, plate, coverage)) < target {
+				return false
+			}
+		}
+		return true
+	}
+
+	low, high := 0, 255
+	for low < high {
+		middle := (low + high) / 2
+		if meets(uint8(middle)) {
+			high = middle
+		} else {
+			low = middle + 1
+		}
+	}
+	return uint8(low)
+}
+
+func withAlpha(c color.NRGBA, alpha uint8) color.NRGBA {
+	c.A = alpha
+	return c
+}
+
+// drawTextPlates paints a rounded plate behind each line in rects. Plates of
+// the same color share one mask, so where padding of neighbouring lines
+// overlaps the plate isn't darker.
+func drawTextPlates(img *image.NRGBA, rects []image.Rectangle, plates []color.NRGBA, fontSize float64) {
+	padX, padY, radius := 0.25*fontSize, 0.08*fontSize, 0.3*fontSize
+
+	masks := map[color.NRGBA]*image.Alpha{}
+	var order []color.NRGBA
+	for i, rect := range rects {
+		key := withAlpha(plates[i], 255)
+		mask, ok := masks[key]
+		if !ok {
+			mask = image.NewAlpha(img.Bounds())
+			masks[key] = mask
+			order = append(order, key)
+		}
+
+		minX, minY := float64(rect.Min.X)-padX, float64(rect.Min.Y)-padY
+		maxX, maxY := float64(rect.Max.X)+padX, float64(rect.Max.Y)+padY
+		area := image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX)), int(math.Ceil(maxY))).Intersect(img.Bounds())
+
+		for y := area.Min.Y; y < area.Max.Y; y++ {
+			for x := area.Min.X; x < area.Max.X; x++ {
+				coverage := roundedRectCoverage(float64(x)+0.5, float64(y)+0.5, minX, minY, maxX, maxY, radius)
+				value := uint8(math.Round(coverage * float64(plates[i].A)))
+				if value > mask.AlphaAt(x, y).A {
+					mask.SetAlpha(x, y, color.Alpha{A: value})
+				}
+			}
+		}
+	}
+
+	for _, key := range order {
+		FillMask(img, masks[key], key)
+	}
+}
+
+// roundedRectCoverage is the antialiased coverage of the point x, y by the
+// rectangle with corners rounded by radius.
+func roundedRectCoverage(x, y, minX, minY, maxX, maxY, radius float64) float64 {
+	radius = min(radius, (maxX-minX)/2, (maxY-minY)/2)
+	dx := max(minX+radius-x, x-(maxX-radius), 0)
+	dy := max(minY+radius-y, y-(maxY-radius), 0)
+	return clampUnit(radius - math.Hypot(dx, dy) + 0.5)
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"testing"
+)
+
+func TestContrastRatio(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     color.NRGBA
+		expected float64
+	}{
+		{name: "Black on white", a: black, b: white, expected: 21},
+		{name: "Order doesn't matter", a: white, b: black, expected: 21},
+		{name: "Same color", a: color.NRGBA{R: 90, G: 140, B: 30, A: 255}, b: color.NRGBA{R: 90, G: 140, B: 30, A: 255}, expected: 1},
+		{name: "Mid grey on white", a: color.NRGBA{R: 118, G: 118, B: 118, A: 255}, b: white, expected: 4.54},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := contrastRatio(tt.a, tt.b); math.Abs(actual-tt.expected) > 0.01 {
+				t.Errorf("Expected %.2f, got %.2f", tt.expected, actual)
+			}
+		})
+	}
+}
+
+// stripedImage alternates rows of two colors, a busy background neither
+// black nor white text can stand out from.
+func stripedImage(size int, a, b color.NRGBA) *image.NRGBA {
+	img := solidImage(size, a)
+	for y := 0; y < size; y += 4 {
+		draw.Draw(img, image.Rect(0, y, size, y+2), image.NewUniform(b), image.Point{}, draw.Src)
+	}
+	return img
+}
+
+func TestContrastStyle(t *testing.T) {
+	rect := image.Rect(10, 10, 90, 40)
+
+	tests := []struct {
+		name          string
+		img           *image.NRGBA
+		style         TextStyle
+		expectedColor color.NRGBA
+		expectPlate   bool
+	}{
+		{
+			name:          "Dark background keeps white text",
+			img:           solidImage(100, color.NRGBA{R: 20, G: 30, B: 60, A: 255}),
+			style:         textStyles["plain"],
+			expectedColor: white,
+		},
+		{
+			name:          "Light background switches to black text",
+			img:           solidImage(100, color.NRGBA{R: 250, G: 240, B: 200, A: 255}),
+			style:         textStyles["plain"],
+			expectedColor: black,
+		},
+		{
+			name:          "Mid tone background picks the better of black and white",
+			img:           solidImage(100, color.NRGBA{R: 230, G: 120, B: 40, A: 255}),
+			style:         textStyles["plain"],
+			expectedColor: black,
+		},
+		{
+			name:          "Busy background gets a plate",
+			img:           stripedImage(100, black, white),
+			style:         textStyles["plain"],
+			expectedColor: white,
+			expectPlate:   true,
+		},
+		{
+			name:          "Styles without a ratio are kept",
+			img:           solidImage(100, white),
+			style:         defaultTextStyle,
+			expectedColor: white,
+		},
+		{
+			name:          "Transparent background is left alone",
+			img:           image.NewNRGBA(image.Rect(0, 0, 100, 100)),
+			style:         textStyles["plain"],
+			expectedColor: white,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			style, plate := contrastStyle(tt.img, rect, tt.style)
+			if style.Color != tt.expectedColor {
+				t.Errorf("Expected text color %v, got %v", tt.expectedColor, style.Color)
+			}
+			if (plate.A > 0) != tt.expectPlate {
+				t.Fatalf("Expected a plate to be %v, got %v", tt.expectPlate, plate)
+			}
+			if !tt.expectPlate {
+				return
+			}
+
+			dark, light, _ := lineBackground(tt.img, rect)
+			for _, background := range []color.NRGBA{dark, light} {
+				behind := CompositeOver(background, withAlpha(plate, 255), uint32(plate.A)*0x101)
+				if ratio := contrastRatio(style.Color, behind); ratio < tt.style.ContrastRatio {
+					t.Errorf("Expected the plate to bring the contrast to %.1f, got %.2f on %v", tt.style.ContrastRatio, ratio, background)
+				}
+			}
+		})
+	}
+}
+
+func TestContrastStyleFlipsOutline(t *testing.T) {
+	style, _ := contrastStyle(solidImage(100, white), image.Rect(10, 10, 90, 40), textStyles["outline-shadow"])
+
+	if style.Color != black {
+		t.Fatalf("Expected black text, got %v", style.Color)
+	}
+	if style.StrokeColor != white {
+		t.Errorf("Expected a white outline around black text, got %v", style.StrokeColor)
+	}
+	if style.ShadowColor != withAlpha(white, textStyles["outline-shadow"].ShadowColor.A) {
+		t.Errorf("Expected a light shadow keeping its opacity, got %v", style.ShadowColor)
+	}
+}
+
+func TestDrawTextToImageContrast(t *testing.T) {
+	tests := []struct {
+		name       string
+		background *image.NRGBA
+		textColor  color.NRGBA
+	}{
+		{name: "Light background", background: solidImage(200, color.NRGBA{R: 245, G: 245, B: 235, A: 255}), textColor: black},
+		{name: "Dark background", background: solidImage(200, color.NRGBA{R: 15, G: 15, B: 35, A: 255}), textColor: white},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := tt.background
+			if _, err := DrawTextToImage(img, "Day off!", defaultFontFamily, textStyles["plain"], defaultTextLayoutOptions); err != nil {
+				t.Fatalf("DrawTextToImage failed: %v", err)
+			}
+
+			textPixels := 0
+			for x := 0; x < 200; x++ {
+				if img.NRGBAAt(x, 100) == tt.textColor {
+					textPixels++
+				}
+			}
+			if textPixels == 0 {
+				t.Errorf("Expected text in %v across the middle", tt.textColor)
+			}
+		})
+	}
+}
+
+func TestDrawTextToImageContrastPlate(t *testing.T) {
+	img := stripedImage(200, black, white)
+	if _, err := DrawTextToImage(img, "Day off!", defaultFontFamily, textStyles["plain"], defaultTextLayoutOptions); err != nil {
+		t.Fatalf("DrawTextToImage failed: %v", err)
+	}
+
+	// Row 100 is a white stripe. White text alone would leave it all white,
+	// the plate darkens it around the glyphs.
+	whitePixels := 0
+	for x := 0; x < 200; x++ {
+		if img.NRGBAAt(x, 100) == white {
+			whitePixels++
+		}
+	}
+	if whitePixels > 150 {
+		t.Errorf("Expected a plate behind the text, got %d white pixels of 200", whitePixels)
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// TextStyle controls how text is painted. Stroke width, shadow offset and
+// shadow blur are fractions of the font size, so the look stays the same when
+// text is scaled to fit the avatar. Zero-alpha colors disable a layer. With a
+// ContrastRatio set, colors are adjusted to the background of each line to
+// keep the text readable, see contrastStyle.
+type TextStyle struct {
+	Color         color.NRGBA
+	ContrastRatio float64
+
+	StrokeColor color.NRGBA
+	StrokeWidth float64
+
+	ShadowColor   color.NRGBA
+	ShadowOffsetX float64
+	ShadowOffsetY float64
+	ShadowBlur    float64
+}
+
+// defaultTextStyle paints plain text in textColor whatever the background.
+var defaultTextStyle = TextStyle{Color: textColor}
+
+var textStyles = map[string]TextStyle{
+	"plain": {
+		Color:         textColor,
+		ContrastRatio: textContrastRatio,
+	},
+	"outline": {
+		Color:         textColor,
+		ContrastRatio: textContrastRatio,
+		StrokeColor:   color.NRGBA{R: 0, G: 0, B: 0, A: 255},
+		StrokeWidth:   0.06,
+	},
+	"shadow": {
+		Color:         textColor,
+		ContrastRatio: textContrastRatio,
+		ShadowColor:   color.NRGBA{R: 0, G: 0, B: 0, A: 180},
+		ShadowOffsetX: 0.04,
+		ShadowOffsetY: 0.06,
+		ShadowBlur:    0.08,
+	},
+	"outline-shadow": {
+		Color:         textColor,
+		ContrastRatio: textContrastRatio,
+		StrokeColor:   color.NRGBA{R: 20, G: 20, B: 40, A: 255},
+		StrokeWidth:   0.05,
+		ShadowColor:   color.NRGBA{R: 0, G: 0, B: 0, A: 140},
+		ShadowOffsetX: 0.03,
+		ShadowOffsetY: 0.05,
+		ShadowBlur:    0.1,
+	},
+}
+
+var textStyleNames = []string{"plain", "outline", "shadow", "outline-shadow"}
+
+// paintText composites the text layers onto img from the bottom up: shadow,
+// stroke, the glyphs themselves and finally color emoji.
+func paintText(img *image.NRGBA, canvas *textCanvas, style TextStyle, fontSize float64) {
+	mask := canvas.glyphs
+	silhouette := canvas.silhouette()
+
+	outline := silhouette
+	if style.StrokeColor.A > 0 && style.StrokeWidth > 0 {
+		outline = dilateMask(silhouette, style.StrokeWidth*fontSize)
+	}
+
+	if style.ShadowColor.A > 0 {
+		shadow := offsetMask(outline, style.ShadowOffsetX*fontSize, style.ShadowOffsetY*fontSize)
+		shadow = blurMask(shadow, style.ShadowBlur*fontSize)
+		FillMask(img, shadow, style.ShadowColor)
+	}
+
+	if outline != silhouette {
+		FillMask(img, outline, style.StrokeColor)
+	}
+
+	FillMask(img, mask, style.Color)
+
+	if canvas.hasEmoji {
+		draw.Draw(img, img.Bounds(), canvas.emoji, img.Bounds().Min, draw.Over)
+	}
+}
+
+// dilateMask grows the coverage by radius pixels with an antialiased round
+// brush, which gives an even outline around glyphs.
+func dilateMask(mask *image.Alpha, radius float64) *image.Alpha {
+	bounds := mask.Bounds()
+	dilated := image.NewAlpha(bounds)
+	reach := int(math.Ceil(radius))
+
+	type offset struct {
+		dx, dy int
+		weight float64
+	}
+	var brush []offset
+	for dy := -reach; dy <= reach; dy++ {
+		for dx := -reach; dx <= reach; dx++ {
+			weight := clampUnit(radius + 0.5 - math.Hypot(float64(dx), float64(dy)))
+			if weight > 0 {
+				brush = append(brush, offset{dx: dx, dy: dy, weight: weight})
+			}
+		}
+	}
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			coverage := float64(mask.AlphaAt(x, y).A)
+			if coverage == 0 {
+				continue
+			}
+
+			for _, o := range brush {
+				px, py := x+o.dx, y+o.dy
+				if !(image.Point{X: px, Y: py}).In(bounds) {
+					continue
+				}
+
+				value := uint8(math.Round(coverage * o.weight))
+				if value > dilated.AlphaAt(px, py).A {
+					dilated.SetAlpha(px, py, color.Alpha{A: value})
+				}
+			}
+		}
+	}
+
+	return dilated
+}
+
+func offsetMask(mask *image.Alpha, dx, dy float64) *image.Alpha {
+	bounds := mask.Bounds()
+	shifted := image.NewAlpha(bounds)
+	offset := image.Pt(int(math.Round(dx)), int(math.Round(dy)))
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			target := image.Pt(x, y).Add(offset)
+			if target.In(bounds) {
+				shifted.SetAlpha(target.X, target.Y, mask.AlphaAt(x, y))
+			}
+		}
+	}
+
+	return shifted
+}
+
+// blurMask approximates a gaussian blur with three box blur passes.
+func blurMask(mask *image.Alpha, radius float64) *image.Alpha {
+	boxRadius := int(math.Round(radius / 2))
+	if boxRadius < 1 {
+		return mask
+	}
+
+	blurred := mask
+	for pass := 0; pass < 3; pass++ {
+		blurred = boxBlurMask(blurred, boxRadius, true)
+		blurred = boxBlurMask(blurred, boxRadius, false)
+	}
+	return blurred
+}
+
+func boxBlurMask(mask *image.Alpha, radius int, horizontal bool) *image.Alpha {
+	bounds := mask.Bounds()
+	blurred := image.NewAlpha(bounds)
+
+	outer, inner := bounds.Dy(), bounds.Dx()
+	at := func(i, j int) (int, int) { return bounds.Min.X + j, bounds.Min.Y + i }
+	if !horizontal {
+		outer, inner = bounds.Dx(), bounds.Dy()
+		at = func(i, j int) (int, int) { return bounds.Min.X + i, bounds.Min.Y + j }
+	}
+
+	window := 2*radius + 1
+	for i := 0; i < outer; i++ {
+		sum := 0
+		for j := -radius; j <= radius; j++ {
+			if j >= 0 && j < inner {
+				sum += int(mask.AlphaAt(at(i, j)).A)
+			}
+		}
+
+		for j := 0; j < inner; j++ {
+			x, y := at(i, j)
+			blurred.SetAlpha(x, y, color.Alpha{A: uint8(sum / window)})
+
+			if leaving := j - radius; leaving >= 0 {
+				sum -= int(mask.AlphaAt(at(i, leaving)).A)
+			}
+			if entering := j + radius + 1; entering < inner {
+				sum += int(mask.AlphaAt(at(i, entering)).A)
+			}
+		}
+	}
+
+	return blurred
+}
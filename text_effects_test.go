@@ -0,0 +1,80 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestDilateMask(t *testing.T) {
+	mask := image.NewAlpha(image.Rect(0, 0, 21, 21))
+	mask.SetAlpha(10, 10, color.Alpha{A: 255})
+
+	dilated := dilateMask(mask, 3)
+
+	tests := []struct {
+		name     string
+		x, y     int
+		expected uint8
+	}{
+		{name: "Center stays covered", x: 10, y: 10, expected: 255},
+		{name: "Inside the radius is covered", x: 12, y: 10, expected: 255},
+		{name: "Edge of the radius is antialiased", x: 13, y: 10, expected: 128},
+		{name: "Outside the radius is empty", x: 14, y: 10, expected: 0},
+		{name: "Diagonal outside the radius is empty", x: 13, y: 13, expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := dilated.AlphaAt(tt.x, tt.y).A; actual != tt.expected {
+				t.Errorf("At (%d,%d) expected %d, got %d", tt.x, tt.y, tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestBlurMaskSpreadsCoverage(t *testing.T) {
+	mask := image.NewAlpha(image.Rect(0, 0, 40, 40))
+	draw.Draw(mask, image.Rect(15, 15, 25, 25), image.Opaque, image.Point{}, draw.Src)
+
+	blurred := blurMask(mask, 6)
+
+	if center := blurred.AlphaAt(20, 20).A; center < 128 {
+		t.Errorf("Expected the center to stay mostly covered, got %d", center)
+	}
+	if edge := blurred.AlphaAt(13, 20).A; edge == 0 || edge == 255 {
+		t.Errorf("Expected a soft edge outside the square, got %d", edge)
+	}
+	if far := blurred.AlphaAt(2, 2).A; far != 0 {
+		t.Errorf("Expected no coverage far from the square, got %d", far)
+	}
+}
+
+func TestDrawTextToImageStyles(t *testing.T) {
+	white := color.NRGBA{255, 255, 255, 255}
+
+	for _, name := range []string{"outline", "shadow", "outline-shadow"} {
+		t.Run(name, func(t *testing.T) {
+			img := image.NewNRGBA(image.Rect(0, 0, 300, 150))
+			draw.Draw(img, img.Bounds(), image.NewUniform(white), image.Point{}, draw.Src)
+
+			_, err := DrawTextToImage(img, "Day off!", defaultFontFamily, textStyles[name], defaultTextLayoutOptions)
+			if err != nil {
+				t.Fatalf("DrawTextToImage failed: %v", err)
+			}
+
+			darkPixels := 0
+			for y := 0; y < img.Bounds().Dy(); y++ {
+				for x := 0; x < img.Bounds().Dx(); x++ {
+					if img.NRGBAAt(x, y).R < 128 {
+						darkPixels++
+					}
+				}
+			}
+			if darkPixels == 0 {
+				t.Error("Expected the style to make white text visible on a white background")
+			}
+		})
+	}
+}
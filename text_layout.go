@@ -0,0 +1,330 @@
+package main
+
+import (
+	"image"
+	"math"
+	"strings"
+
+	"golang.org/x/image/font"
+)
+
+const baseFontSize = 48
+
+// TextLayoutOptions limits how text is broken into lines. MinFontSize is a
+// fraction of the image height so it means the same on small and large
+// avatars; text that can't be shown at that size is shortened. With Circle
+// set, every line is fitted inside the inscribed circle instead of the padded
+// rectangle, so nothing is clipped when the avatar is shown round. A positive
+// Reveal draws only that many characters of the laid out text, in the spots
+// they take in the full text, for typing animations.
+type TextLayoutOptions struct {
+	MaxLines    int
+	MinFontSize float64
+	Circle      bool
+	Reveal      int
+}
+
+var defaultTextLayoutOptions = TextLayoutOptions{
+	MaxLines:    3,
+	MinFontSize: 0.06,
+}
+
+type TextLayout struct {
+	Lines     []string
+	FontSize  float64
+	Truncated bool
+}
+
+const ellipsis = "…"
+
+// circleSafeMargin is kept between text and the edge of the circle, as a
+// fraction of the radius. It also leaves room for the output ring.
+const circleSafeMargin = 0.1
+
+func textPadding(bounds image.Rectangle) (float64, float64) {
+	const horizontalPaddingPercent = 10.0
+	const verticalPaddingPercent = 10.0
+
+	return float64(bounds.Dx()) * horizontalPaddingPercent / 100.0,
+		float64(bounds.Dy()) * verticalPaddingPercent / 100.0
+}
+
+// LayoutText breaks text into lines that fit the padded box of bounds. Line
+// breaks typed by the user are kept. Words are wrapped onto more lines only
+// when the text would otherwise be smaller than comfortable, and wrapped lines
+// are balanced so they have similar widths. face must be a baseFontSize face.
+func LayoutText(face font.Face, text string, bounds image.Rectangle, options TextLayoutOptions) TextLayout {
+	horizontalPadding, verticalPadding := textPadding(bounds)
+	imageWidth, imageHeight := float64(bounds.Dx()), float64(bounds.Dy())
+	minFontSize := options.MinFontSize * imageHeight
+	comfortableFontSize := 2 * minFontSize
+	drawer := &font.Drawer{Face: face}
+
+	_, _, radius := inscribedCircle(bounds)
+	safeRadius := radius * (1 - circleSafeMargin)
+	lineHeight := measureMultilineTextHeight(face, 1)
+
+	fontSizeFor := func(lines []string) float64 {
+		if options.Circle {
+			widths := make([]float64, len(lines))
+			for i, line := range lines {
+				widths[i] = measureLineWidth(drawer, line)
+			}
+			return baseFontSize * circleScaleFactor(widths, lineHeight, safeRadius)
+		}
+
+		textWidth, textHeight := measureMultilineTextSize(face, lines)
+		return baseFontSize * calculateScaleFactor(textWidth, textHeight, imageWidth, imageHeight, horizontalPadding, verticalPadding)
+	}
+
+	paragraphs := strings.Split(text, "\n")
+	if options.MaxLines > 0 && len(paragraphs) > options.MaxLines {
+		paragraphs = []string{strings.Join(paragraphs, " ")}
+	}
+
+	maxLines := options.MaxLines
+	if maxLines < len(paragraphs) {
+		maxLines = len(paragraphs)
+	}
+
+	var best TextLayout
+	for linesCount := len(paragraphs); linesCount <= maxLines; linesCount++ {
+		lines, ok := breakParagraphs(drawer, paragraphs, linesCount)
+		if !ok {
+			break
+		}
+
+		layout := TextLayout{Lines: lines, FontSize: fontSizeFor(lines)}
+		if best.Lines == nil || layout.FontSize > best.FontSize {
+			best = layout
+		}
+		if layout.FontSize >= comfortableFontSize {
+			return layout
+		}
+	}
+
+	if best.FontSize >= minFontSize {
+		return best
+	}
+
+	fullText := strings.Join(strings.Fields(strings.Join(paragraphs, " ")), " ")
+	maxLineWidth := (imageWidth - 2*horizontalPadding) * baseFontSize / minFontSize
+	if options.Circle {
+		// The outermost of maxLines lines at the minimum size has the
+		// shortest chord, using it for every line keeps them all inside.
+		halfHeight := float64(maxLines) * lineHeight * minFontSize / baseFontSize / 2
+		maxLineWidth = chordWidth(safeRadius, halfHeight) * baseFontSize / minFontSize
+	}
+	lines := truncateToLines(drawer, fullText, maxLineWidth, maxLines)
+
+	return TextLayout{
+		Lines:     lines,
+		FontSize:  fontSizeFor(lines),
+		Truncated: strings.Join(lines, " ") != fullText,
+	}
+}
+
+// chordWidth is the width of a circle of the given radius at distance
+// from its center.
+func chordWidth(radius, distance float64) float64 {
+	if distance >= radius {
+		return 0
+	}
+	return 2 * math.Sqrt(radius*radius-distance*distance)
+}
+
+// circleScaleFactor finds the largest scale at which lines of the given
+// widths and line height, stacked and centered in a circle of radius, each
+// fit the chord at the edge of their line box farthest from the center.
+func circleScaleFactor(widths []float64, lineHeight, radius float64) float64 {
+	fits := func(scale float64) bool {
+		height := lineHeight * scale
+		top := -height * float64(len(widths)) / 2
+
+		for i, width := range widths {
+			lineTop := top + float64(i)*height
+			distance := math.Max(math.Abs(lineTop), math.Abs(lineTop+height))
+			if width*scale > chordWidth(radius, distance) {
+				return false
+			}
+		}
+		return true
+	}
+
+	widest := 0.0
+	for _, width := range widths {
+		widest = math.Max(widest, width)
+	}
+
+	low, high := 0.0, 2*radius/math.Max(widest, lineHeight*float64(len(widths)))
+	for i := 0; i < 32; i++ {
+		middle := (low + high) / 2
+		if fits(middle) {
+			low = middle
+		} else {
+			high = middle
+		}
+	}
+	return low
+}
+
+// breakParagraphs splits every paragraph into lines so that linesCount lines
+// are used in total, giving extra lines to the widest paragraphs first. It
+// reports false when there are not enough words to fill that many lines.
+func breakParagraphs(drawer *font.Drawer, paragraphs []string, linesCount int) ([]string, bool) {
+	words := make([][]string, len(paragraphs))
+	counts := make([]int, len(paragraphs))
+	widths := make([]float64, len(paragraphs))
+	spare := linesCount - len(paragraphs)
+
+	for i, paragraph := range paragraphs {
+		words[i] = strings.Fields(paragraph)
+		counts[i] = 1
+		widths[i] = measureLineWidth(drawer, paragraph)
+	}
+
+	for ; spare > 0; spare-- {
+		widest := -1
+		for i := range paragraphs {
+			if counts[i] >= len(words[i]) {
+				continue
+			}
+			if widest == -1 || widths[i]/float64(counts[i]) > widths[widest]/float64(counts[widest]) {
+				widest = i
+			}
+		}
+		if widest == -1 {
+			return nil, false
+		}
+		counts[widest]++
+	}
+
+	var lines []string
+	for i, paragraph := range paragraphs {
+		if counts[i] == 1 {
+			lines = append(lines, paragraph)
+			continue
+		}
+		lines = append(lines, balanceWords(drawer, words[i], counts[i])...)
+	}
+	return lines, true
+}
+
+// balanceWords splits words into exactly linesCount lines minimizing the
+// widest line, which keeps wrapped lines visually even. Every word is measured
+// once, a line is as wide as its words and the spaces between them.
+func balanceWords(drawer *font.Drawer, words []string, linesCount int) []string {
+	n := len(words)
+	spaceWidth := measureLineWidth(drawer, " ")
+
+	// prefix[i] is the width of the first i words without spaces.
+	prefix := make([]float64, n+1)
+	for i, word := range words {
+		prefix[i+1] = prefix[i] + measureLineWidth(drawer, word)
+	}
+	width := func(from, to int) float64 {
+		return prefix[to] - prefix[from] + spaceWidth*float64(to-from-1)
+	}
+
+	// cost[k][i] is the smallest possible widest line when the first i words
+	// are split into k lines, split[k][i] is where the last of those lines starts.
+	cost := make([][]float64, linesCount+1)
+	split := make([][]int, linesCount+1)
+	for k := range cost {
+		cost[k] = make([]float64, n+1)
+		split[k] = make([]int, n+1)
+		for i := range cost[k] {
+			cost[k][i] = math.Inf(1)
+		}
+	}
+	cost[0][0] = 0
+
+	for k := 1; k <= linesCount; k++ {
+		for i := k; i <= n; i++ {
+			for j := k - 1; j < i; j++ {
+				candidate := math.Max(cost[k-1][j], width(j, i))
+				if candidate < cost[k][i] {
+					cost[k][i] = candidate
+					split[k][i] = j
+				}
+			}
+		}
+	}
+
+	lines := make([]string, linesCount)
+	for k, i := linesCount, n; k > 0; k-- {
+		j := split[k][i]
+		lines[k-1] = strings.Join(words[j:i], " ")
+		i = j
+	}
+	return lines
+}
+
+// truncateToLines fills at most maxLines lines of maxWidth greedily and marks
+// the cut with an ellipsis. Words longer than a line are cut as well.
+func truncateToLines(drawer *font.Drawer, text string, maxWidth float64, maxLines int) []string {
+	fits := func(line string) bool {
+		return measureLineWidth(drawer, line) <= maxWidth
+	}
+
+	var lines []string
+	current := ""
+	words := strings.Fields(text)
+
+	for i := 0; i < len(words); i++ {
+		candidate := words[i]
+		if current != "" {
+			candidate = current + " " + words[i]
+		}
+
+		if fits(candidate) {
+			current = candidate
+			continue
+		}
+
+		if current == "" {
+			current = cutToWidth(drawer, words[i], maxWidth)
+			if len(lines)+1 < maxLines {
+				lines = append(lines, current)
+				current = ""
+				continue
+			}
+			return append(lines, current)
+		}
+
+		if len(lines)+1 == maxLines {
+			return append(lines, cutToWidth(drawer, current, maxWidth))
+		}
+		lines = append(lines, current)
+		current = ""
+		i--
+	}
+
+	if current != "" || len(lines) == 0 {
+		lines = append(lines, current)
+	}
+	return lines
+}
+
+// cutToWidth shortens text to fit maxWidth together with an ellipsis,
+// dropping whole words first and only cutting inside a word when it is alone.
+func cutToWidth(drawer *font.Drawer, text string, maxWidth float64) string {
+	words := strings.Fields(text)
+	for len(words) > 1 {
+		candidate := strings.Join(words, " ") + ellipsis
+		if measureLineWidth(drawer, candidate) <= maxWidth {
+			return candidate
+		}
+		words = words[:len(words)-1]
+	}
+
+	runes := []rune(strings.Join(words, " "))
+	for len(runes) > 0 {
+		candidate := strings.TrimRight(string(runes), " ") + ellipsis
+		if measureLineWidth(drawer, candidate) <= maxWidth {
+			return candidate
+		}
+		runes = runes[:len(runes)-1]
+	}
+	return ellipsis
+}
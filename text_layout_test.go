@@ -0,0 +1,188 @@
+package main
+
+import (
+	"image"
+	"math"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/font"
+)
+
+func TestLayoutText(t *testing.T) {
+	ttfFont, err := CachedLoadDefaultFont()
+	if err != nil {
+		t.Fatalf("Failed to load font: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		text          string
+		size          int
+		options       TextLayoutOptions
+		expectedLines []string
+		truncated     bool
+	}{
+		{
+			name:          "Short text stays on one line",
+			text:          "Day off!",
+			size:          400,
+			options:       defaultTextLayoutOptions,
+			expectedLines: []string{"Day off!"},
+		},
+		{
+			name:          "User line breaks are kept",
+			text:          "On\nvacation!",
+			size:          400,
+			options:       defaultTextLayoutOptions,
+			expectedLines: []string{"On", "vacation!"},
+		},
+		{
+			name:          "Long text is wrapped into balanced lines",
+			text:          "This is a very long text that might need scaling",
+			size:          400,
+			options:       defaultTextLayoutOptions,
+			expectedLines: []string{"This is a very long", "text that might", "need scaling"},
+		},
+		{
+			name:          "Wrapping respects the maximum line count",
+			text:          "This is a very long text that might need scaling",
+			size:          400,
+			options:       TextLayoutOptions{MaxLines: 2, MinFontSize: 0.06},
+			expectedLines: []string{"This is a very long text", "that might need scaling"},
+		},
+		{
+			name:          "Too long text is shortened with an ellipsis",
+			text:          strings.Repeat("vacation ", 40),
+			size:          400,
+			options:       TextLayoutOptions{MaxLines: 2, MinFontSize: 0.1},
+			expectedLines: []string{"vacation vacation", "vacation…"},
+			truncated:     true,
+		},
+		{
+			name:          "Too long word is cut",
+			text:          "Supercalifragilisticexpialidocious-antidisestablishmentarianism",
+			size:          400,
+			options:       TextLayoutOptions{MaxLines: 2, MinFontSize: 0.1},
+			expectedLines: []string{"Supercalifragili…"},
+			truncated:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bounds := image.Rect(0, 0, tt.size, tt.size)
+			layout := LayoutText(ttfFont.defaultFace, tt.text, bounds, tt.options)
+
+			if strings.Join(layout.Lines, "|") != strings.Join(tt.expectedLines, "|") {
+				t.Errorf("Expected lines %q, got %q", tt.expectedLines, layout.Lines)
+			}
+			if layout.Truncated != tt.truncated {
+				t.Errorf("Expected truncated %v, got %v", tt.truncated, layout.Truncated)
+			}
+			if minFontSize := tt.options.MinFontSize * float64(tt.size); layout.FontSize < minFontSize*0.99 {
+				t.Errorf("Expected font size of at least %.1f, got %.1f", minFontSize, layout.FontSize)
+			}
+		})
+	}
+}
+
+func TestCircleScaleFactor(t *testing.T) {
+	tests := []struct {
+		name       string
+		widths     []float64
+		lineHeight float64
+		radius     float64
+		expected   float64
+	}{
+		{
+			name:       "Single line touches the circle with its corners",
+			widths:     []float64{300},
+			lineHeight: 40,
+			radius:     100,
+			expected:   200 / math.Hypot(300, 40),
+		},
+		{
+			name:       "Two equal lines meet the circle at the outer corners",
+			widths:     []float64{100, 100},
+			lineHeight: 50,
+			radius:     100,
+			expected:   200 / math.Hypot(100, 100),
+		},
+		{
+			name:       "A short middle line doesn't limit the scale",
+			widths:     []float64{30, 10, 30},
+			lineHeight: 10,
+			radius:     100,
+			expected:   200 / math.Hypot(30, 30),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := circleScaleFactor(tt.widths, tt.lineHeight, tt.radius)
+			if math.Abs(actual-tt.expected) > 1e-6 {
+				t.Errorf("Expected %f, got %f", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDrawTextToImageInsideCircle(t *testing.T) {
+	texts := []string{"Out of office", "On vacation until the end of the month", "Gone fishing\nBack soon"}
+	options := defaultTextLayoutOptions
+	options.Circle = true
+
+	for _, text := range texts {
+		t.Run(text, func(t *testing.T) {
+			img := image.NewNRGBA(image.Rect(0, 0, 300, 300))
+			if _, err := DrawTextToImage(img, text, defaultFontFamily, defaultTextStyle, options); err != nil {
+				t.Fatalf("DrawTextToImage failed: %v", err)
+			}
+
+			centerX, centerY, radius := inscribedCircle(img.Bounds())
+			drawn := 0
+			for y := 0; y < 300; y++ {
+				for x := 0; x < 300; x++ {
+					if img.NRGBAAt(x, y).A == 0 {
+						continue
+					}
+					drawn++
+					if circleCoverage(x, y, centerX, centerY, radius) < 1 {
+						t.Fatalf("Text pixel (%d,%d) is outside the circle", x, y)
+					}
+				}
+			}
+			if drawn == 0 {
+				t.Error("Expected text to be drawn")
+			}
+		})
+	}
+}
+
+func TestBalanceWordsKeepsEveryWord(t *testing.T) {
+	ttfFont, err := CachedLoadDefaultFont()
+	if err != nil {
+		t.Fatalf("Failed to load font: %v", err)
+	}
+	drawer := &font.Drawer{Face: ttfFont.defaultFace}
+
+	words := strings.Fields(strings.Repeat("out of office until the end of the month ", 60))
+	lines := balanceWords(drawer, words, 8)
+
+	if len(lines) != 8 {
+		t.Fatalf("Expected 8 lines, got %d", len(lines))
+	}
+	if strings.Join(lines, " ") != strings.Join(words, " ") {
+		t.Error("Expected the lines to hold every word in order")
+	}
+
+	widest, narrowest := 0.0, math.Inf(1)
+	for _, line := range lines {
+		width := measureLineWidth(drawer, line)
+		widest, narrowest = math.Max(widest, width), math.Min(narrowest, width)
+	}
+	if widest-narrowest > measureLineWidth(drawer, "office ") {
+		t.Errorf("Expected balanced lines, widths range from %.1f to %.1f", narrowest, widest)
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+type BannerKind uint8
+
+const (
+	BannerNone BannerKind = iota
+	BannerSolid
+	BannerGradient
+)
+
+// TextPosition is where text goes on the avatar. Area is the part the text
+// is fitted into, nil for all of it. With AvoidFaces the text moves next to
+// the faces in the avatar when there are any. A banner is a strip across the
+// avatar behind the text, as high as the text block with some padding.
+type TextPosition struct {
+	Area       *Area
+	AvoidFaces bool
+	Banner     BannerKind
+}
+
+var textPositions = map[string]TextPosition{
+	"auto":            {AvoidFaces: true},
+	"center":          {},
+	"top":             {Area: &Area{X: 0, Y: 0, Width: 1, Height: 1.0 / 3}},
+	"bottom":          {Area: &Area{X: 0, Y: 2.0 / 3, Width: 1, Height: 1.0 / 3}},
+	"top-left":        {Area: &Area{X: 0.05, Y: 0.05, Width: 0.5, Height: 0.35}},
+	"top-right":       {Area: &Area{X: 0.45, Y: 0.05, Width: 0.5, Height: 0.35}},
+	"bottom-left":     {Area: &Area{X: 0.05, Y: 0.6, Width: 0.5, Height: 0.35}},
+	"bottom-right":    {Area: &Area{X: 0.45, Y: 0.6, Width: 0.5, Height: 0.35}},
+	"banner":          {Area: &Area{X: 0, Y: 2.0 / 3, Width: 1, Height: 1.0 / 3}, Banner: BannerSolid},
+	"gradient-banner": {Area: &Area{X: 0, Y: 2.0 / 3, Width: 1, Height: 1.0 / 3}, Banner: BannerGradient},
+}
+
+var textPositionNames = []string{
+	"auto", "center", "top", "bottom",
+	"top-left", "top-right", "bottom-left", "bottom-right",
+	"banner", "gradient-banner",
+}
+
+const defaultTextPosition = "auto"
+
+// Banner look. The padding above and below the text is a fraction of the
+// font size.
+var bannerColor = color.NRGBA{R: 0, G: 0, B: 0, A: 150}
+
+const (
+	bannerPadding         = 0.35
+	bannerGradientOpacity = 0.85
+)
+
+// positionRect returns the rectangle of bounds the text is fitted into. For
+// round output it is moved inside the circle: bands across the avatar are cut
+// to the widest part that fits, other areas are shrunk towards the center.
+func positionRect(bounds image.Rectangle, position TextPosition, circle bool) image.Rectangle {
+	rect := position.Area.rect(bounds)
+	if !circle || position.Area == nil {
+		return rect
+	}
+	if position.Area.Width == 1 {
+		return bandInCircle(bounds, rect, true)
+	}
+	return shrinkIntoCircle(bounds, rect)
+}
+
+// shrinkIntoCircle scales rect down towards its point nearest the center of
+// bounds until its corners are within the circle inscribed in bounds, with
+// circleSafeMargin kept from its edge.
+func shrinkIntoCircle(bounds, rect image.Rectangle) image.Rectangle {
+	centerX, centerY, radius := inscribedCircle(bounds)
+	radius *= 1 - circleSafeMargin
+
+	anchorX := math.Max(float64(rect.Min.X), math.Min(centerX, float64(rect.Max.X)))
+	anchorY := math.Max(float64(rect.Min.Y), math.Min(centerY, float64(rect.Max.Y)))
+	scaled := func(scale float64) (float64, float64, float64, float64) {
+		return anchorX + (float64(rect.Min.X)-anchorX)*scale, anchorY + (float64(rect.Min.Y)-anchorY)*scale,
+			anchorX + (float64(rect.Max.X)-anchorX)*scale, anchorY + (float64(rect.Max.Y)-anchorY)*scale
+	}
+	inside := func(scale float64) bool {
+		minX, minY, maxX, maxY := scaled(scale)
+		for _, corner := range [][2]float64{{minX, minY}, {maxX, minY}, {minX, maxY}, {maxX, maxY}} {
+			if math.Hypot(corner[0]-centerX, corner[1]-centerY) > radius {
+				return false
+			}
+		}
+		return true
+	}
+
+	low, high := 0.0, 1.0
+	if inside(high) {
+		return rect
+	}
+	for i := 0; i < 32; i++ {
+		middle := (low + high) / 2
+		if inside(middle) {
+			low = middle
+		} else {
+			high = middle
+		}
+	}
+
+	minX, minY, maxX, maxY := scaled(low)
+	return image.Rect(int(math.Ceil(minX)), int(math.Ceil(minY)), int(maxX), int(maxY))
+}
+
+// textBlockRect is the rectangle DrawTextToImage fills with text in img, its
+// size measured with measureMultilineTextSize. The font size is returned
+// along with it.
+func textBlockRect(img *image.NRGBA, text, fontFamily string, options TextLayoutOptions) (image.Rectangle, float64, error) {
+	bounds := img.Bounds()
+
+	ttfFont, err := CachedLoadFontFamily(fontFamily)
+	if err != nil {
+		return image.Rectangle{}, 0, err
+	}
+
+	layout := LayoutText(ttfFont.defaultFace, text, bounds, options)
+	face, err := ttfFont.NewFace(layout.FontSize)
+	if err != nil {
+		return image.Rectangle{}, 0, err
+	}
+	defer face.Close()
+
+	width, height := measureMultilineTextSize(face, layout.Lines)
+	centerX := float64(bounds.Min.X+bounds.Max.X) / 2
+	centerY := float64(bounds.Min.Y+bounds.Max.Y) / 2
+
+	return image.Rect(
+		int(centerX-width/2), int(centerY-height/2),
+		int(math.Ceil(centerX+width/2)), int(math.Ceil(centerY+height/2)),
+	), layout.FontSize, nil
+}
+
+// drawBanner paints the strip for text fitted into area of img. The strip
+// runs across the whole avatar, as high as the text block plus padding and
+// never beyond area.
+func drawBanner(img *image.NRGBA, area image.Rectangle, text, fontFamily string, options TextLayoutOptions, kind BannerKind, settings UserSettings) error {
+	block, fontSize, err := textBlockRect(img.SubImage(area).(*image.NRGBA), text, fontFamily, options)
+	if err != nil {
+		return err
+	}
+
+	padding := int(math.Round(bannerPadding * fontSize))
+	bounds := img.Bounds()
+	strip := image.Rect(bounds.Min.X, max(block.Min.Y-padding, area.Min.Y), bounds.Max.X, min(block.Max.Y+padding, area.Max.Y))
+	if strip.Empty() {
+		return nil
+	}
+
+	switch kind {
+	case BannerSolid:
+		mask := image.NewAlpha(strip)
+		draw.Draw(mask, strip, image.Opaque, image.Point{}, draw.Src)
+		FillMask(img, mask, bannerColor)
+
+	case BannerGradient:
+		spec := settings.GradientSpec()
+		if settings.Palette == autoGradientPalette {
+			spec.Stops = AvatarPalette(img)
+		}
+		spec.Shape = LinearGradient(90)
+
+		gradient := CreateGradient(strip.Dx(), strip.Dy(), spec)
+		fill := image.NewNRGBA(strip)
+		draw.Draw(fill, strip, gradient, image.Point{}, draw.Src)
+
+		mask := image.NewAlpha(strip)
+		draw.Draw(mask, strip, image.NewUniform(color.Alpha{A: uint8(math.Round(bannerGradientOpacity * 255))}), image.Point{}, draw.Src)
+		FillMaskImage(img, mask, fill)
+	}
+
+	return nil
+}
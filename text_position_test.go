@@ -0,0 +1,160 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestPositionRect(t *testing.T) {
+	bounds := image.Rect(0, 0, 300, 300)
+
+	tests := []struct {
+		name     string
+		position string
+		expected image.Rectangle
+	}{
+		{name: "Center uses the whole avatar", position: "center", expected: bounds},
+		{name: "Top third", position: "top", expected: image.Rect(0, 0, 300, 100)},
+		{name: "Bottom third", position: "bottom", expected: image.Rect(0, 200, 300, 300)},
+		{name: "Top left corner", position: "top-left", expected: image.Rect(15, 15, 165, 119)},
+		{name: "Top right corner", position: "top-right", expected: image.Rect(135, 15, 285, 119)},
+		{name: "Bottom left corner", position: "bottom-left", expected: image.Rect(15, 180, 165, 285)},
+		{name: "Bottom right corner", position: "bottom-right", expected: image.Rect(135, 180, 285, 285)},
+		{name: "Banner", position: "banner", expected: image.Rect(0, 200, 300, 300)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := positionRect(bounds, textPositions[tt.position], false); actual != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestPositionRectInsideCircle(t *testing.T) {
+	bounds := image.Rect(0, 0, 300, 300)
+	centerX, centerY, radius := inscribedCircle(bounds)
+
+	for _, name := range []string{"top", "bottom", "top-left", "top-right", "bottom-left", "bottom-right", "banner"} {
+		t.Run(name, func(t *testing.T) {
+			rect := positionRect(bounds, textPositions[name], true)
+			if rect.Empty() || !rect.In(positionRect(bounds, textPositions[name], false)) {
+				t.Fatalf("Expected a part of the position area, got %v", rect)
+			}
+
+			for _, corner := range []image.Point{rect.Min, {rect.Max.X, rect.Min.Y}, {rect.Min.X, rect.Max.Y}, rect.Max} {
+				if distance := math.Hypot(float64(corner.X)-centerX, float64(corner.Y)-centerY); distance > radius*(1-circleSafeMargin)+1 {
+					t.Errorf("Expected %v inside the circle, corner %v is %.1f from the center", rect, corner, distance)
+				}
+			}
+		})
+	}
+}
+
+func TestTextBlockRect(t *testing.T) {
+	img := solidImage(300, color.NRGBA{R: 40, G: 40, B: 40, A: 255})
+	area := img.SubImage(image.Rect(0, 200, 300, 300)).(*image.NRGBA)
+
+	block, fontSize, err := textBlockRect(area, "Out of office", defaultFontFamily, defaultTextLayoutOptions)
+	if err != nil {
+		t.Fatalf("textBlockRect failed: %v", err)
+	}
+
+	if !block.In(area.Bounds()) {
+		t.Errorf("Expected the block %v inside the area %v", block, area.Bounds())
+	}
+	if block.Dy() < int(fontSize) || block.Dy() > area.Bounds().Dy() {
+		t.Errorf("Expected the block to be about a line high at font size %.1f, got %v", fontSize, block)
+	}
+}
+
+func TestRenderTemplateTextPositions(t *testing.T) {
+	background := color.NRGBA{R: 40, G: 90, B: 140, A: 255}
+
+	tests := []struct {
+		name     string
+		position string
+		// Rows that must stay untouched and rows expected to change.
+		still, changed image.Rectangle
+	}{
+		{name: "Top", position: "top", still: image.Rect(0, 110, 300, 300), changed: image.Rect(0, 0, 300, 100)},
+		{name: "Bottom", position: "bottom", still: image.Rect(0, 0, 300, 190), changed: image.Rect(0, 200, 300, 300)},
+		{name: "Top left corner", position: "top-left", still: image.Rect(170, 0, 300, 300), changed: image.Rect(15, 15, 165, 119)},
+		{name: "Bottom right corner", position: "bottom-right", still: image.Rect(0, 0, 130, 300), changed: image.Rect(135, 180, 285, 285)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := solidImage(300, background)
+			settings := defaultUserSettings()
+			settings.TextStyle = textStyles["plain"]
+			settings.TextPosition = textPositions[tt.position]
+
+			tmpl := Template{Name: "text", Layers: []Layer{{Type: LayerText}}}
+			if _, err := RenderTemplate(img, tmpl, "Day off!", settings); err != nil {
+				t.Fatalf("RenderTemplate failed: %v", err)
+			}
+
+			if count := changedPixels(img, tt.still, background); count > 0 {
+				t.Errorf("Expected nothing drawn in %v, got %d pixels", tt.still, count)
+			}
+			if count := changedPixels(img, tt.changed, background); count == 0 {
+				t.Errorf("Expected text in %v", tt.changed)
+			}
+		})
+	}
+}
+
+func TestRenderTemplateBanner(t *testing.T) {
+	background := color.NRGBA{R: 200, G: 200, B: 200, A: 255}
+
+	for _, name := range []string{"banner", "gradient-banner"} {
+		t.Run(name, func(t *testing.T) {
+			img := solidImage(300, background)
+			settings := defaultUserSettings()
+			settings.TextPosition = textPositions[name]
+
+			tmpl := Template{Name: "text", Layers: []Layer{{Type: LayerText}}}
+			if _, err := RenderTemplate(img, tmpl, "Day off!", settings); err != nil {
+				t.Fatalf("RenderTemplate failed: %v", err)
+			}
+
+			area := positionRect(img.Bounds(), settings.TextPosition, false)
+			block, fontSize, err := textBlockRect(solidImage(300, background).SubImage(area).(*image.NRGBA), "Day off!", defaultFontFamily, defaultTextLayoutOptions)
+			if err != nil {
+				t.Fatalf("textBlockRect failed: %v", err)
+			}
+			padding := int(math.Ceil(bannerPadding * fontSize))
+
+			// The left edge is clear of text, so it shows where the strip is.
+			for y := 0; y < 300; y++ {
+				inStrip := img.NRGBAAt(0, y) != background
+				switch {
+				case y >= block.Min.Y && y < block.Max.Y && !inStrip:
+					t.Fatalf("Expected the strip behind the text at row %d", y)
+				case (y < area.Min.Y || y < block.Min.Y-padding || y >= block.Max.Y+padding) && inStrip:
+					t.Fatalf("Expected the strip to hug the text in %v, found it at row %d", area, y)
+				}
+			}
+			if img.NRGBAAt(299, block.Min.Y) == background {
+				t.Errorf("Expected the strip across the whole avatar")
+			}
+		})
+	}
+}
+
+func changedPixels(img *image.NRGBA, rect image.Rectangle, background color.NRGBA) int {
+	count := 0
+	rect = rect.Intersect(img.Bounds())
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			if img.NRGBAAt(x, y) != background {
+				count++
+			}
+		}
+	}
+	return count
+}
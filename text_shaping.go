@@ -0,0 +1,264 @@
+package main
+
+import (
+	"image"
+	"image/draw"
+	"math"
+	"unicode"
+
+	"github.com/go-text/typesetting/di"
+	gotextfont "github.com/go-text/typesetting/font"
+	"github.com/go-text/typesetting/shaping"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+	"golang.org/x/text/unicode/bidi"
+)
+
+// complexScripts need contextual forms, ligatures or glyph reordering that
+// drawing rune by rune can't produce. Only scripts fallbackFontPaths cover
+// are listed, the shaper can't do better than .notdef for the rest.
+var complexScripts = []*unicode.RangeTable{
+	unicode.Arabic, unicode.Hebrew, unicode.Nko,
+	unicode.Devanagari, unicode.Tamil, unicode.Kannada,
+	unicode.Thai, unicode.Myanmar,
+}
+
+// needsShaping reports text that has to go through the shaper. Everything
+// else is drawn with font.Drawer, which is faster and gives the same result
+// for simple left-to-right scripts.
+func needsShaping(text string) bool {
+	for _, r := range text {
+		if isRightToLeft(r) || unicode.Is(unicode.Mn, r) || unicode.In(r, complexScripts...) {
+			return true
+		}
+	}
+	return false
+}
+
+func isRightToLeft(r rune) bool {
+	props, _ := bidi.LookupRune(r)
+	class := props.Class()
+	return class == bidi.R || class == bidi.AL
+}
+
+// baseDirection picks the paragraph direction from the first strong
+// character, as the Unicode bidi algorithm does.
+func baseDirection(text string) di.Direction {
+	for _, r := range text {
+		props, _ := bidi.LookupRune(r)
+		switch props.Class() {
+		case bidi.L:
+			return di.DirectionLTR
+		case bidi.R, bidi.AL:
+			return di.DirectionRTL
+		}
+	}
+	return di.DirectionLTR
+}
+
+type shapedGlyph struct {
+	font int
+	id   sfnt.GlyphIndex
+	// x and y are relative to the line origin on the baseline, y grows down.
+	x, y    fixed.Int26_6
+	advance fixed.Int26_6
+}
+
+// shapedLine holds glyphs in visual order, left to right.
+type shapedLine struct {
+	glyphs  []shapedGlyph
+	advance fixed.Int26_6
+}
+
+type shapingFontmap []*gotextfont.Face
+
+func (faces shapingFontmap) ResolveFace(r rune) *gotextfont.Face {
+	for _, face := range faces {
+		if _, ok := face.NominalGlyph(r); ok {
+			return face
+		}
+	}
+	return faces[0]
+}
+
+// shapeLine runs bidi segmentation, script itemization and OpenType shaping
+// over a single line of text at the given size in pixels.
+func (fc *FontCache) shapeLine(text string, size float64) shapedLine {
+	faces := make(shapingFontmap, len(fc.shapingFonts))
+	for i, ttf := range fc.shapingFonts {
+		faces[i] = gotextfont.NewFace(ttf)
+	}
+
+	runes := []rune(text)
+	direction := baseDirection(text)
+	input := shaping.Input{
+		Text:      runes,
+		RunStart:  0,
+		RunEnd:    len(runes),
+		Direction: direction,
+		Size:      fixed.Int26_6(size * 64),
+	}
+
+	var segmenter shaping.Segmenter
+	var shaper shaping.HarfbuzzShaper
+
+	var line shapedLine
+	for _, runInput := range visualOrder(direction, segmenter.Split(input, faces)) {
+		run := shaper.Shape(runInput)
+
+		fontIndex := 0
+		for i, face := range faces {
+			if face == run.Face {
+				fontIndex = i
+			}
+		}
+
+		for _, glyph := range run.Glyphs {
+			line.glyphs = append(line.glyphs, shapedGlyph{
+				font:    fontIndex,
+				id:      sfnt.GlyphIndex(glyph.GlyphID),
+				x:       line.advance + glyph.XOffset,
+				y:       -glyph.YOffset,
+				advance: glyph.XAdvance,
+			})
+			line.advance += glyph.XAdvance
+		}
+	}
+	return line
+}
+
+// visualOrder arranges runs left to right: spans against the paragraph
+// direction are reversed, then the whole line is reversed for right-to-left
+// paragraphs. Numbers that follow right-to-left text in a left-to-right
+// paragraph stay inside its span, as the bidi algorithm embeds them there.
+// Glyphs inside a run come out of the shaper already in visual order.
+func visualOrder(direction di.Direction, runs []shaping.Input) []shaping.Input {
+	ordered := make([]shaping.Input, len(runs))
+	copy(ordered, runs)
+
+	for start := 0; start < len(ordered); {
+		if ordered[start].Direction == direction {
+			start++
+			continue
+		}
+		end := start
+		for end < len(ordered) && (ordered[end].Direction != direction || isWeakRun(ordered[end])) {
+			end++
+		}
+		reverseRuns(ordered[start:end])
+		start = end
+	}
+
+	if direction == di.DirectionRTL {
+		reverseRuns(ordered)
+	}
+	return ordered
+}
+
+// isWeakRun reports runs without strongly left-to-right characters, such as
+// digits and punctuation.
+func isWeakRun(run shaping.Input) bool {
+	for _, r := range run.Text[run.RunStart:run.RunEnd] {
+		props, _ := bidi.LookupRune(r)
+		if props.Class() == bidi.L {
+			return false
+		}
+	}
+	return true
+}
+
+func reverseRuns(runs []shaping.Input) {
+	for i, j := 0, len(runs)-1; i < j; i, j = i+1, j-1 {
+		runs[i], runs[j] = runs[j], runs[i]
+	}
+}
+
+// drawShapedLine rasterizes shaped glyphs as coverage into mask, with dot on
+// the baseline at the left edge of the line.
+func (fc *FontCache) drawShapedLine(mask *image.Alpha, line shapedLine, size float64, dot fixed.Point26_6) {
+	var buf sfnt.Buffer
+	ppem := fixed.Int26_6(size * 64)
+
+	for _, glyph := range line.glyphs {
+		segments, err := fc.fonts[glyph.font].LoadGlyph(&buf, glyph.id, ppem, nil)
+		if err != nil {
+			continue
+		}
+
+		originX := float64(dot.X+glyph.x) / 64
+		originY := float64(dot.Y+glyph.y) / 64
+		drawGlyphSegments(mask, segments, func(x, y float64) (float64, float64) {
+			return originX + x, originY + y
+		})
+	}
+}
+
+// drawGlyphSegments rasterizes a glyph outline into mask. transform maps
+// glyph coordinates in pixels, relative to the glyph origin, to mask
+// coordinates; it must be affine so transformed control points still bound
+// the curves.
+func drawGlyphSegments(mask *image.Alpha, segments sfnt.Segments, transform func(x, y float64) (float64, float64)) {
+	var minX, minY, maxX, maxY float64
+	first := true
+	for _, segment := range segments {
+		for _, arg := range segment.Args[:segmentArgsCount(segment.Op)] {
+			x, y := transform(float64(arg.X)/64, float64(arg.Y)/64)
+			if first {
+				minX, minY, maxX, maxY = x, y, x, y
+				first = false
+				continue
+			}
+			minX, minY = math.Min(minX, x), math.Min(minY, y)
+			maxX, maxY = math.Max(maxX, x), math.Max(maxY, y)
+		}
+	}
+
+	rect := image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX)), int(math.Ceil(maxY)))
+	if first || rect.Empty() {
+		return
+	}
+
+	point := func(p fixed.Point26_6) (float32, float32) {
+		x, y := transform(float64(p.X)/64, float64(p.Y)/64)
+		return float32(x - float64(rect.Min.X)), float32(y - float64(rect.Min.Y))
+	}
+
+	var rasterizer vector.Rasterizer
+	rasterizer.Reset(rect.Dx(), rect.Dy())
+
+	for _, segment := range segments {
+		switch segment.Op {
+		case sfnt.SegmentOpMoveTo:
+			rasterizer.MoveTo(point(segment.Args[0]))
+		case sfnt.SegmentOpLineTo:
+			rasterizer.LineTo(point(segment.Args[0]))
+		case sfnt.SegmentOpQuadTo:
+			x1, y1 := point(segment.Args[0])
+			x2, y2 := point(segment.Args[1])
+			rasterizer.QuadTo(x1, y1, x2, y2)
+		case sfnt.SegmentOpCubeTo:
+			x1, y1 := point(segment.Args[0])
+			x2, y2 := point(segment.Args[1])
+			x3, y3 := point(segment.Args[2])
+			rasterizer.CubeTo(x1, y1, x2, y2, x3, y3)
+		}
+	}
+	rasterizer.ClosePath()
+
+	// The rasterizer doesn't clip, so the glyph goes through its own mask.
+	glyph := image.NewAlpha(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	rasterizer.Draw(glyph, glyph.Bounds(), image.Opaque, image.Point{})
+	draw.Draw(mask, rect, glyph, image.Point{}, draw.Over)
+}
+
+func segmentArgsCount(op sfnt.SegmentOp) int {
+	switch op {
+	case sfnt.SegmentOpQuadTo:
+		return 2
+	case sfnt.SegmentOpCubeTo:
+		return 3
+	default:
+		return 1
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"image"
+	"math"
+	"testing"
+
+	"github.com/go-text/typesetting/di"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+)
+
+func TestNeedsShaping(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected bool
+	}{
+		{name: "Latin", text: "Hello World!", expected: false},
+		{name: "Cyrillic", text: "Привет", expected: false},
+		{name: "Arabic", text: "مرحبا", expected: true},
+		{name: "Hebrew", text: "שלום", expected: true},
+		{name: "Devanagari", text: "नमस्ते", expected: true},
+		{name: "Tamil", text: "வணக்கம்", expected: true},
+		{name: "Thai", text: "สวัสดี", expected: true},
+		{name: "Combining mark", text: "e\u0301", expected: true},
+		{name: "Mixed", text: "Hi שלום", expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := needsShaping(tt.text); actual != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestBaseDirection(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected di.Direction
+	}{
+		{name: "Latin", text: "Hello", expected: di.DirectionLTR},
+		{name: "Hebrew", text: "שלום", expected: di.DirectionRTL},
+		{name: "First strong character wins", text: "123 שלום Hello", expected: di.DirectionRTL},
+		{name: "No strong characters", text: "123 !", expected: di.DirectionLTR},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := baseDirection(tt.text); actual != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func glyphIndices(t *testing.T, ttfFont *FontCache, fontIndex int, text string) []sfnt.GlyphIndex {
+	var buf sfnt.Buffer
+	var indices []sfnt.GlyphIndex
+	for _, r := range text {
+		index, err := ttfFont.fonts[fontIndex].GlyphIndex(&buf, r)
+		if err != nil || index == 0 {
+			t.Fatalf("Font %d has no glyph for %q", fontIndex, r)
+		}
+		indices = append(indices, index)
+	}
+	return indices
+}
+
+func TestShapeLineHasGlyphsForBundledScripts(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{name: "Japanese", text: "休暇中です、来週戻ります"},
+		{name: "Traditional Chinese", text: "休假中 下週回來"},
+		{name: "Simplified Chinese", text: "我们这周休假"},
+		{name: "Korean", text: "휴가 중입니다"},
+		{name: "Devanagari", text: "छुट्टी पर हूँ"},
+		{name: "Tamil", text: "விடுமுறையில் இருக்கிறேன்"},
+		{name: "Kannada", text: "ರಜೆಯಲ್ಲಿದ್ದೇನೆ"},
+		{name: "Thai", text: "ลาพักร้อน"},
+		{name: "Myanmar", text: "အားလပ်ရက်"},
+		{name: "Georgian", text: "შვებულებაში"},
+		{name: "Armenian", text: "Արձակուրդում"},
+	}
+
+	for _, family := range fontFamilyNames {
+		ttfFont, err := CachedLoadFontFamily(family)
+		if err != nil {
+			t.Fatalf("Failed to load %s: %v", family, err)
+		}
+
+		for _, tt := range tests {
+			t.Run(family+" "+tt.name, func(t *testing.T) {
+				line := ttfFont.shapeLine(tt.text, baseFontSize)
+				for i, glyph := range line.glyphs {
+					if glyph.id == 0 {
+						t.Errorf("Glyph %d of %q is .notdef", i, tt.text)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestShapeLineOrdersHebrewRightToLeft(t *testing.T) {
+	ttfFont, err := CachedLoadDefaultFont()
+	if err != nil {
+		t.Fatalf("Failed to load fonts: %v", err)
+	}
+
+	line := ttfFont.shapeLine("של", baseFontSize)
+	expected := glyphIndices(t, ttfFont, 1, "לש")
+
+	if len(line.glyphs) != len(expected) {
+		t.Fatalf("Expected %d glyphs, got %d", len(expected), len(line.glyphs))
+	}
+	for i, glyph := range line.glyphs {
+		if glyph.id != expected[i] {
+			t.Errorf("Glyph %d: expected %d, got %d", i, expected[i], glyph.id)
+		}
+		if i > 0 && glyph.x <= line.glyphs[i-1].x {
+			t.Errorf("Expected glyph %d to be right of glyph %d", i, i-1)
+		}
+	}
+}
+
+func TestShapeLineJoinsArabicLetters(t *testing.T) {
+	ttfFont, err := CachedLoadDefaultFont()
+	if err != nil {
+		t.Fatalf("Failed to load fonts: %v", err)
+	}
+
+	isolated := glyphIndices(t, ttfFont, 1, "ب")[0]
+	line := ttfFont.shapeLine("ببب", baseFontSize)
+
+	if len(line.glyphs) != 3 {
+		t.Fatalf("Expected 3 glyphs, got %d", len(line.glyphs))
+	}
+	for i, glyph := range line.glyphs {
+		if glyph.id == isolated {
+			t.Errorf("Expected glyph %d to use a contextual form, got the isolated one", i)
+		}
+	}
+	if line.glyphs[0].id == line.glyphs[1].id || line.glyphs[1].id == line.glyphs[2].id {
+		t.Error("Expected final, medial and initial forms to differ")
+	}
+}
+
+func TestShapeLineKeepsNumbersWithRightToLeftText(t *testing.T) {
+	ttfFont, err := CachedLoadDefaultFont()
+	if err != nil {
+		t.Fatalf("Failed to load fonts: %v", err)
+	}
+
+	one := glyphIndices(t, ttfFont, 0, "1")[0]
+	shin := glyphIndices(t, ttfFont, 1, "ש")[0]
+
+	position := func(line shapedLine, id sfnt.GlyphIndex) int {
+		for i, glyph := range line.glyphs {
+			if glyph.id == id {
+				return i
+			}
+		}
+		t.Fatalf("Glyph %d is missing", id)
+		return -1
+	}
+
+	line := ttfFont.shapeLine("Hi שלום 12", baseFontSize)
+	if position(line, one) > position(line, shin) {
+		t.Error("Expected the number to be drawn left of the Hebrew word")
+	}
+}
+
+func TestMeasureLineWidthUsesShaping(t *testing.T) {
+	ttfFont, err := CachedLoadDefaultFont()
+	if err != nil {
+		t.Fatalf("Failed to load fonts: %v", err)
+	}
+
+	text := "مرحبا بالعالم"
+	line := ttfFont.shapeLine(text, baseFontSize)
+
+	drawer := &font.Drawer{Face: ttfFont.defaultFace}
+	if actual, expected := measureLineWidth(drawer, text), float64(line.advance)/64; actual != expected {
+		t.Errorf("Expected width %.2f, got %.2f", expected, actual)
+	}
+}
+
+func TestDrawTextToImageCentersRightToLeftText(t *testing.T) {
+	for _, text := range []string{"مرحبا بالعالم", "שלום עולם"} {
+		t.Run(text, func(t *testing.T) {
+			img := image.NewNRGBA(image.Rect(0, 0, 400, 200))
+			_, err := DrawTextToImage(img, text, defaultFontFamily, defaultTextStyle, defaultTextLayoutOptions)
+			if err != nil {
+				t.Fatalf("DrawTextToImage failed: %v", err)
+			}
+
+			drawn := image.Rectangle{}
+			for y := 0; y < img.Bounds().Dy(); y++ {
+				for x := 0; x < img.Bounds().Dx(); x++ {
+					if img.NRGBAAt(x, y).A > 0 {
+						drawn = drawn.Union(image.Rect(x, y, x+1, y+1))
+					}
+				}
+			}
+
+			if drawn.Empty() {
+				t.Fatal("Expected glyphs to be drawn")
+			}
+			center := float64(drawn.Min.X+drawn.Max.X) / 2
+			if math.Abs(center-200) > 10 {
+				t.Errorf("Expected text centered around x=200, got %.1f", center)
+			}
+		})
+	}
+}